@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/route-2/evm-go/evm"
+)
+
+// sample is run when neither -code nor -codefile is given: 5 * 5.
+const sample = "0x600560050200"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the program described by args, printing the outcome to stdout
+// and any trace to stderr, and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("evm", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	code := flags.String("code", "", "hex-encoded bytecode to run")
+	codeFile := flags.String("codefile", "", "file holding hex-encoded bytecode to run")
+	gas := flags.Uint64("gas", 1000, "gas available to execution")
+	callData := flags.String("calldata", "", "hex-encoded call data")
+	trace := flags.Bool("json", false, "write a JSON struct-log trace to stderr")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	src := *code
+	switch {
+	case src != "" && *codeFile != "":
+		fmt.Fprintln(stderr, "Error: -code and -codefile are mutually exclusive")
+		return 2
+	case *codeFile != "":
+		data, err := os.ReadFile(*codeFile)
+		if err != nil {
+			fmt.Fprintln(stderr, "Error:", err)
+			return 1
+		}
+		src = strings.TrimSpace(string(data))
+	case src == "":
+		src = sample
+	}
+	bytecode, err := evm.FromHex(src)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error: invalid code:", err)
+		return 2
+	}
+	input, err := evm.FromHex(*callData)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error: invalid calldata:", err)
+		return 2
+	}
+
+	opts := []evm.Option{evm.WithGas(*gas), evm.WithCallData(input)}
+	var tracer *evm.JSONTracer
+	if *trace {
+		tracer = evm.NewJSONTracer(stderr)
+		opts = append(opts, evm.WithTracer(tracer))
+	}
+	machine := evm.NewEVM(opts...)
+	result := machine.Execute(bytecode)
+
+	fmt.Fprintln(stdout, machine.Stack())
+	fmt.Fprintf(stdout, "Gas used: %d\n", result.GasUsed)
+	fmt.Fprintf(stdout, "Remaining gas: %d\n", result.GasLeft)
+	if len(result.ReturnData) > 0 {
+		fmt.Fprintf(stdout, "Return data: 0x%x\n", result.ReturnData)
+	}
+	if tracer != nil && tracer.Err() != nil {
+		fmt.Fprintln(stderr, "Error: writing trace:", tracer.Err())
+	}
+	switch {
+	case result.Err != nil:
+		fmt.Fprintln(stdout, "Error:", result.Err)
+		return 1
+	case result.Reverted:
+		fmt.Fprintln(stdout, "Reverted")
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package evm
+
+import "math"
+
+// accessList tracks the accounts and storage slots touched so far in a
+// transaction, which EIP-2929 prices more cheaply on every access after the
+// first. It is shared by every frame of the transaction.
+type accessList struct {
+	addresses map[[20]byte]bool
+	slots     map[slotKey]bool
+}
+
+func newAccessList() *accessList {
+	return &accessList{
+		addresses: make(map[[20]byte]bool),
+		slots:     make(map[slotKey]bool),
+	}
+}
+
+// addressAccess records that an account became warm.
+type addressAccess struct {
+	addr [20]byte
+}
+
+func (c addressAccess) revert(evm *EVM) {
+	delete(evm.access.addresses, c.addr)
+}
+
+// slotAccess records that a storage slot became warm.
+type slotAccess struct {
+	slot slotKey
+}
+
+func (c slotAccess) revert(evm *EVM) {
+	delete(evm.access.slots, c.slot)
+}
+
+// accessAddress marks addr warm, reporting whether it was cold. Like other
+// state changes it is undone if the frame reverts.
+func (evm *EVM) accessAddress(addr [20]byte) bool {
+	if evm.access.addresses[addr] {
+		return false
+	}
+	evm.access.addresses[addr] = true
+	evm.journal = append(evm.journal, addressAccess{addr: addr})
+	return true
+}
+
+// accessSlot marks a storage slot of the executing contract warm, reporting
+// whether it was cold.
+func (evm *EVM) accessSlot(key Hash) bool {
+	slot := slotKey{addr: evm.ctx.Address, key: key}
+	if evm.access.slots[slot] {
+		return false
+	}
+	evm.access.slots[slot] = true
+	evm.journal = append(evm.journal, slotAccess{slot: slot})
+	return true
+}
+
+// useAccessLists switches the opcode table to EIP-2929 pricing, where SLOAD
+// and the opcodes that touch another account pay the warm price up front and
+// a surcharge on first access, and warms the accounts every transaction
+// starts with.
+func (evm *EVM) useAccessLists() {
+	sload := evm.opcodes[0x54]
+	sload.gasCost = evm.schedule.WarmAccess
+	sload.dynamicGas = gasSloadAccess
+	evm.opcodes[0x54] = sload
+
+	// Each of these takes the account it touches as the n-th stack item.
+	for op, n := range map[uint64]int{0x31: 0, 0x3b: 0, 0x3c: 0, 0x3f: 0, 0xf1: 1, 0xf2: 1, 0xf4: 1, 0xfa: 1} {
+		entry := evm.opcodes[op]
+		entry.gasCost = evm.schedule.WarmAccess
+		entry.dynamicGas = withAccountAccess(n, entry.dynamicGas)
+		evm.opcodes[op] = entry
+	}
+	evm.warmAccessList()
+}
+
+// warmAccessList adds the accounts and slots every transaction starts with to
+// the access list.
+func (evm *EVM) warmAccessList() {
+	evm.access.addresses[evm.ctx.Origin] = true
+	evm.access.addresses[evm.ctx.Caller] = true
+	evm.access.addresses[evm.ctx.Address] = true
+	if evm.config.Fork >= Shanghai {
+		// EIP-3651
+		evm.access.addresses[evm.ctx.Coinbase] = true
+	}
+	for addr, p := range precompiles {
+		if p.fork <= evm.config.Fork {
+			evm.access.addresses[addr] = true
+		}
+	}
+	for _, tuple := range evm.ctx.AccessList {
+		evm.access.addresses[tuple.Address] = true
+		for _, key := range tuple.StorageKeys {
+			evm.access.slots[slotKey{addr: tuple.Address, key: key}] = true
+		}
+	}
+}
+
+// gasSloadAccess charges the cold surcharge the first time a slot is loaded.
+func gasSloadAccess(evm *EVM) uint64 {
+	if evm.accessSlot(Hash(evm.peek(0).Bytes32())) {
+		return evm.schedule.ColdSload - evm.schedule.WarmAccess
+	}
+	return 0
+}
+
+// withAccountAccess wraps an opcode's dynamic gas function, which may be nil,
+// to also charge the cold surcharge the first time the account given by the
+// n-th stack item is touched.
+func withAccountAccess(n int, dynamicGas func(*EVM) uint64) func(*EVM) uint64 {
+	return func(evm *EVM) uint64 {
+		var gas uint64
+		if dynamicGas != nil {
+			gas = dynamicGas(evm)
+			if gas == math.MaxUint64 {
+				return gas
+			}
+		}
+		if evm.accessAddress(evm.peek(n).Bytes20()) {
+			gas += evm.schedule.ColdAccount - evm.schedule.WarmAccess
+		}
+		return gas
+	}
+}
@@ -0,0 +1,67 @@
+package evm
+
+import "github.com/holiman/uint256"
+
+// jumpdestAnalysis scans the bytecode once and returns the offsets of every
+// JUMPDEST that is an actual instruction rather than PUSH immediate data.
+func jumpdestAnalysis(bytecode []byte) map[int]bool {
+	dests := make(map[int]bool)
+	for pc := 0; pc < len(bytecode); pc++ {
+		op := bytecode[pc]
+		if op == 0x5b {
+			dests[pc] = true
+		} else if 0x60 <= op && op <= 0x7f {
+			pc += int(op - 0x5f)
+		}
+	}
+	return dests
+}
+
+// AnalysisReport describes what can be learned about bytecode without
+// running it. Offsets are positions in the code.
+type AnalysisReport struct {
+	// JumpDests holds the offsets of the valid jump destinations.
+	JumpDests map[int]bool
+	// InvalidOpcodes lists the offsets of bytes that are not an opcode in
+	// any fork, including the designated INVALID (0xfe).
+	InvalidOpcodes []int
+	// TruncatedPush is the offset of a PUSH whose immediate runs past the end
+	// of the code, or -1 if there is none.
+	TruncatedPush int
+	// BadJumps lists the offsets of JUMP and JUMPI instructions directly
+	// preceded by a PUSH of a target that is not a jump destination.
+	BadJumps []int
+}
+
+// Analyze inspects bytecode for problems that are visible statically. Only
+// jumps whose target is pushed immediately before them can be checked.
+func Analyze(bytecode []byte) AnalysisReport {
+	report := AnalysisReport{
+		JumpDests:     jumpdestAnalysis(bytecode),
+		TruncatedPush: -1,
+	}
+	// target is the value of the previous instruction if it was a PUSH.
+	var target *uint256.Int
+	for pc := 0; pc < len(bytecode); pc++ {
+		op := bytecode[pc]
+		pushed := target
+		target = nil
+		switch {
+		case 0x5f <= op && op <= 0x7f:
+			n := int(op - 0x5f)
+			if pc+n >= len(bytecode) {
+				report.TruncatedPush = pc
+				return report
+			}
+			target = new(uint256.Int).SetBytes(bytecode[pc+1 : pc+1+n])
+			pc += n
+		case op == 0x56 || op == 0x57:
+			if pushed != nil && (!pushed.IsUint64() || !report.JumpDests[int(pushed.Uint64())]) {
+				report.BadJumps = append(report.BadJumps, pc)
+			}
+		case OpName(uint64(op)) == "INVALID":
+			report.InvalidOpcodes = append(report.InvalidOpcodes, pc)
+		}
+	}
+	return report
+}
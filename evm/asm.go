@@ -0,0 +1,137 @@
+package evm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Assemble translates mnemonics into bytecode, one instruction per line.
+// Everything after a ';' is a comment. A line of the form "name:" defines a
+// label at the current offset, normally placed just before a JUMPDEST.
+//
+// PUSH1 through PUSH32 take a hex or decimal literal, left-padded to the
+// width of the opcode. A bare PUSH picks the width from the literal: the
+// number of digits of a hex literal, or the fewest bytes that hold a decimal
+// one. A literal may also be "@name", which pushes the offset of a label and
+// may refer forward; a bare PUSH uses two bytes for it.
+func Assemble(src string) ([]byte, error) {
+	type fixup struct {
+		pos, width, line int
+		label            string
+	}
+	var (
+		code   []byte
+		labels = map[string]int{}
+		fixups []fixup
+	)
+	for i, line := range strings.Split(src, "\n") {
+		n := i + 1
+		if c := strings.IndexByte(line, ';'); c >= 0 {
+			line = line[:c]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) == 1 && strings.HasSuffix(fields[0], ":") {
+			name := strings.TrimSuffix(fields[0], ":")
+			if _, ok := labels[name]; ok {
+				return nil, fmt.Errorf("line %d: label %q redefined", n, name)
+			}
+			labels[name] = len(code)
+			continue
+		}
+		mnemonic := strings.ToUpper(fields[0])
+		width := 0
+		if mnemonic != "PUSH" {
+			op, ok := opcodeByName(mnemonic)
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown mnemonic %q", n, fields[0])
+			}
+			if op < 0x60 || op > 0x7f {
+				if len(fields) != 1 {
+					return nil, fmt.Errorf("line %d: %s takes no operand", n, mnemonic)
+				}
+				code = append(code, op)
+				continue
+			}
+			width = int(op - 0x5f)
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: %s takes one operand", n, mnemonic)
+		}
+		operand := fields[1]
+		if strings.HasPrefix(operand, "@") {
+			if width == 0 {
+				width = 2
+			}
+			code = append(code, byte(0x5f+width))
+			fixups = append(fixups, fixup{pos: len(code), width: width, line: n, label: operand[1:]})
+			code = append(code, make([]byte, width)...)
+			continue
+		}
+		data, err := parseLiteral(operand)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if width == 0 {
+			width = len(data)
+		}
+		if len(data) > width {
+			return nil, fmt.Errorf("line %d: %s does not fit in %s", n, operand, mnemonic)
+		}
+		code = append(code, byte(0x5f+width))
+		code = append(code, make([]byte, width-len(data))...)
+		code = append(code, data...)
+	}
+	for _, f := range fixups {
+		offset, ok := labels[f.label]
+		if !ok {
+			return nil, fmt.Errorf("line %d: undefined label %q", f.line, f.label)
+		}
+		for i := f.pos + f.width - 1; i >= f.pos; i-- {
+			code[i] = byte(offset)
+			offset >>= 8
+		}
+		if offset != 0 {
+			return nil, fmt.Errorf("line %d: offset of label %q does not fit in %d bytes", f.line, f.label, f.width)
+		}
+	}
+	return code, nil
+}
+
+// parseLiteral decodes a hex or decimal PUSH operand into its big-endian
+// bytes. A hex literal keeps its written width; a decimal one is minimal.
+func parseLiteral(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		digits := s[2:]
+		if len(digits)%2 == 1 {
+			digits = "0" + digits
+		}
+		data, err := hex.DecodeString(digits)
+		if err != nil || len(data) == 0 || len(data) > 32 {
+			return nil, fmt.Errorf("invalid literal %q", s)
+		}
+		return data, nil
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 || v.BitLen() > 256 {
+		return nil, fmt.Errorf("invalid literal %q", s)
+	}
+	if v.Sign() == 0 {
+		return []byte{0}, nil
+	}
+	return v.Bytes(), nil
+}
+
+// opcodeByName returns the opcode with the given mnemonic.
+func opcodeByName(name string) (byte, bool) {
+	for op, n := range opcodeNames {
+		if n == name {
+			return byte(op), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,54 @@
+package evm
+
+import "testing"
+
+// BenchmarkExecute runs a loop that counts down from 10000, adding and
+// multiplying on each iteration, to exercise dispatch and jumps.
+func BenchmarkExecute(b *testing.B) {
+	code, err := Assemble(`
+	PUSH2 10000   ; counter
+	loop:
+	JUMPDEST
+	PUSH1 3
+	PUSH1 5
+	ADD
+	PUSH1 7
+	MUL
+	POP
+	PUSH1 1
+	SWAP1
+	SUB           ; counter - 1
+	DUP1
+	PUSH @loop
+	JUMPI
+	STOP`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	evm := NewEVM()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		evm.Reset(10000000)
+		if result := evm.Execute(code); result.Err != nil {
+			b.Fatal(result.Err)
+		}
+	}
+}
+
+// BenchmarkArithmetic isolates ADD and MUL: straight-line code with no jumps.
+func BenchmarkArithmetic(b *testing.B) {
+	code := []byte{0x60, 1}
+	for i := 0; i < 500; i++ {
+		code = append(code, 0x60, 3, 0x01, 0x60, 5, 0x02) // PUSH1 3 ADD PUSH1 5 MUL
+	}
+	evm := NewEVM()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		evm.Reset(100000)
+		if result := evm.Execute(code); result.Err != nil {
+			b.Fatal(result.Err)
+		}
+	}
+}
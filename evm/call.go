@@ -0,0 +1,233 @@
+package evm
+
+import (
+	"errors"
+	"math"
+
+	"github.com/holiman/uint256"
+)
+
+// maxCallDepth is the deepest a call or create may nest. A frame at this depth
+// that tries to call or create fails without running the callee.
+const maxCallDepth = 1024
+
+// callGas returns the gas to forward to a callee: what the caller asked for,
+// capped at all but one 64th of the gas it has available (EIP-150).
+func callGas(available, requested uint64) uint64 {
+	return min(requested, available-available/64)
+}
+
+// requestedGas reads the gas a call asks for from the stack, saturating at
+// the largest uint64.
+func requestedGas(gas *uint256.Int) uint64 {
+	if !gas.IsUint64() {
+		return math.MaxUint64
+	}
+	return gas.Uint64()
+}
+
+func (evm *EVM) opCall(bytecode []byte) bool {
+	gas := evm.pop()
+	addr := evm.pop()
+	value := evm.pop()
+	if evm.readOnly && !value.IsZero() {
+		return evm.fail(ErrWriteProtection)
+	}
+	input, ret, retSize := evm.popCallRanges()
+
+	forwarded := callGas(evm.gas, requestedGas(&gas))
+	evm.gas -= forwarded
+	if !value.IsZero() {
+		forwarded += gasCallStipend
+	}
+	output, gasLeft, ok := evm.call(addr.Bytes20(), &value, input, forwarded, false)
+	return evm.finishCall(output, gasLeft, ok, ret, retSize)
+}
+
+func (evm *EVM) opCallcode(bytecode []byte) bool {
+	gas := evm.pop()
+	addr := evm.pop()
+	value := evm.pop()
+	input, ret, retSize := evm.popCallRanges()
+
+	forwarded := callGas(evm.gas, requestedGas(&gas))
+	evm.gas -= forwarded
+	if !value.IsZero() {
+		forwarded += gasCallStipend
+	}
+	output, gasLeft, ok := evm.callCode(addr.Bytes20(), &value, input, forwarded)
+	return evm.finishCall(output, gasLeft, ok, ret, retSize)
+}
+
+func (evm *EVM) opStaticcall(bytecode []byte) bool {
+	gas := evm.pop()
+	addr := evm.pop()
+	input, ret, retSize := evm.popCallRanges()
+
+	forwarded := callGas(evm.gas, requestedGas(&gas))
+	evm.gas -= forwarded
+	output, gasLeft, ok := evm.call(addr.Bytes20(), new(uint256.Int), input, forwarded, true)
+	return evm.finishCall(output, gasLeft, ok, ret, retSize)
+}
+
+func (evm *EVM) opDelegatecall(bytecode []byte) bool {
+	gas := evm.pop()
+	addr := evm.pop()
+	input, ret, retSize := evm.popCallRanges()
+
+	forwarded := callGas(evm.gas, requestedGas(&gas))
+	evm.gas -= forwarded
+	output, gasLeft, ok := evm.delegateCall(addr.Bytes20(), input, forwarded)
+	return evm.finishCall(output, gasLeft, ok, ret, retSize)
+}
+
+// popCallRanges pops the argument and return ranges common to the CALL family
+// and expands memory to cover both. It returns a copy of the argument bytes
+// and the return range.
+func (evm *EVM) popCallRanges() (input []byte, ret, retSize int) {
+	argsOffset := evm.pop()
+	argsLength := evm.pop()
+	retOffset := evm.pop()
+	retLength := evm.pop()
+	args, argsSize, _ := toMemoryRange(&argsOffset, &argsLength)
+	ret, retSize, _ = toMemoryRange(&retOffset, &retLength)
+	evm.expandMemory(args, argsSize)
+	evm.expandMemory(ret, retSize)
+	return evm.readMemory(args, argsSize), ret, retSize
+}
+
+// finishCall refunds the gas a callee left, keeps its output for
+// RETURNDATACOPY, copies as much of it as fits into the return range, and
+// pushes 1 for success or 0 for failure.
+func (evm *EVM) finishCall(output []byte, gasLeft uint64, ok bool, ret, retSize int) bool {
+	evm.gas += gasLeft
+	evm.returnBuffer = output
+	evm.writeMemory(ret, output[:min(len(output), retSize)])
+	var success uint256.Int
+	setBool(&success, ok)
+	return evm.push(&success)
+}
+
+// call runs the code at to in a child frame, transferring value from the
+// executing contract first. If readOnly is set the child, and any calls it
+// makes, may not modify state. It returns the callee's output, which is the
+// revert data if it reverted, the gas it did not use, and whether it
+// succeeded. A failed call leaves the state unchanged.
+func (evm *EVM) call(to [20]byte, value *uint256.Int, input []byte, gas uint64, readOnly bool) ([]byte, uint64, bool) {
+	snapshot := evm.snapshot()
+	if !evm.transfer(evm.ctx.Address, to, value) {
+		return nil, gas, false
+	}
+
+	ctx := evm.ctx
+	ctx.Caller = evm.ctx.Address
+	ctx.Address = to
+	ctx.CallValue = value.ToBig()
+	ctx.CallData = input
+	return evm.callFrame(snapshot, to, ctx, gas, readOnly)
+}
+
+// transfer moves value from one account to another, reporting false and
+// changing nothing if the sender cannot afford it.
+func (evm *EVM) transfer(from, to [20]byte, value *uint256.Int) bool {
+	if value.IsZero() {
+		return true
+	}
+	balance := evm.state.GetBalance(from)
+	if balance.Lt(value) {
+		return false
+	}
+	evm.setBalance(from, balance.Sub(balance, value))
+	toBalance := evm.state.GetBalance(to)
+	evm.setBalance(to, toBalance.Add(toBalance, value))
+	return true
+}
+
+// delegateCall runs the code at to in the executing contract's own context:
+// the child keeps its address, caller and call value, so storage reads and
+// writes act on the executing contract rather than on to.
+func (evm *EVM) delegateCall(to [20]byte, input []byte, gas uint64) ([]byte, uint64, bool) {
+	ctx := evm.ctx
+	ctx.CallData = input
+	return evm.callFrame(evm.snapshot(), to, ctx, gas, false)
+}
+
+// callCode runs the code at to against the executing contract's own storage
+// and balance, like delegateCall, but as a new message from the executing
+// contract carrying value. The value never leaves the account, but the call
+// fails if the account cannot afford it.
+func (evm *EVM) callCode(to [20]byte, value *uint256.Int, input []byte, gas uint64) ([]byte, uint64, bool) {
+	if evm.state.GetBalance(evm.ctx.Address).Lt(value) {
+		return nil, gas, false
+	}
+	ctx := evm.ctx
+	ctx.Caller = evm.ctx.Address
+	ctx.CallValue = value.ToBig()
+	ctx.CallData = input
+	return evm.callFrame(evm.snapshot(), to, ctx, gas, false)
+}
+
+// callFrame runs the code at to, or the precompile there, with the given
+// context, and reverts to snapshot if it fails.
+func (evm *EVM) callFrame(snapshot int, to [20]byte, ctx Context, gas uint64, readOnly bool) ([]byte, uint64, bool) {
+	if evm.depth >= maxCallDepth {
+		evm.revertTo(snapshot)
+		return nil, gas, false
+	}
+	if p, ok := evm.precompile(to); ok {
+		output, gasLeft, ok := runPrecompile(p, ctx.CallData, gas)
+		if !ok {
+			evm.revertTo(snapshot)
+		}
+		return output, gasLeft, ok
+	}
+	result := evm.runFrame(ctx, evm.state.GetCode(to), gas, readOnly)
+	if result.Err != nil || result.Reverted {
+		evm.revertTo(snapshot)
+	}
+	if result.Err != nil {
+		// A fault has consumed all the gas given to the callee; only the
+		// step limit, which aborts the whole execution, leaves any.
+		return nil, result.GasLeft, false
+	}
+	return result.ReturnData, result.GasLeft, !result.Reverted
+}
+
+// runFrame executes code in a child EVM with the given context and gas. The
+// child shares the caller's state, journal and logs, so its changes are undone
+// if the caller later reverts past them. A child of a read-only frame is
+// always read-only. Steps count against a single MaxSteps budget across all
+// frames, and a child that reaches it fails the caller with the same error.
+func (evm *EVM) runFrame(ctx Context, code []byte, gas uint64, readOnly bool) ExecutionResult {
+	child := &EVM{
+		stack:     NewStack(),
+		memory:    []byte{},
+		state:     evm.state,
+		transient: evm.transient,
+		access:    evm.access,
+		originals: evm.originals,
+		destructs: evm.destructs,
+		refund:    evm.refund,
+		journal:   evm.journal,
+		logs:      evm.logs,
+		gas:       gas,
+		opcodes:   evm.opcodes,
+		ctx:       ctx,
+		config:    evm.config,
+		schedule:  evm.schedule,
+		tracer:    evm.tracer,
+		readOnly:  evm.readOnly || readOnly,
+		depth:     evm.depth + 1,
+		stepBase:  evm.stepBase + evm.steps,
+		MaxSteps:  evm.MaxSteps,
+	}
+	result := child.Execute(code)
+	evm.journal = child.journal
+	evm.logs = child.logs
+	evm.refund = child.refund
+	evm.steps += child.steps
+	if errors.Is(result.Err, ErrStepLimit) {
+		evm.err = result.Err
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package evm
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestStepLimitAcrossCalls checks that MaxSteps bounds the instructions of
+// every frame together and that a callee reaching it aborts the caller too,
+// without burning the gas that was left.
+func TestStepLimitAcrossCalls(t *testing.T) {
+	loop := [20]byte{19: 0x99}
+	state := NewMemoryState(map[[20]byte]Account{
+		loop: {Code: []byte{0x5b, 0x60, 0, 0x56}}, // JUMPDEST PUSH1 0 JUMP
+	})
+	outer, err := Assemble(`
+	PUSH1 0   ; retSize
+	PUSH1 0   ; retOffset
+	PUSH1 0   ; argsSize
+	PUSH1 0   ; argsOffset
+	PUSH1 0   ; value
+	PUSH1 0x99
+	GAS
+	CALL`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evm := NewEVM(WithGas(1000000), WithState(state), WithMaxSteps(1000))
+	result := evm.Execute(outer)
+	if !errors.Is(result.Err, ErrStepLimit) || result.Success {
+		t.Fatalf("err = %v, success = %v; want ErrStepLimit", result.Err, result.Success)
+	}
+	if evm.steps != 1000 {
+		t.Errorf("steps = %d, want 1000", evm.steps)
+	}
+	if result.GasLeft == 0 {
+		t.Error("the step limit burned the remaining gas")
+	}
+}
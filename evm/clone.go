@@ -0,0 +1,37 @@
+package evm
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/holiman/uint256"
+)
+
+// Clone returns an independent copy of the machine, including its stack,
+// memory, pc, gas, return data and transaction bookkeeping, so that both can
+// continue executing separately. A MemoryState is copied along with it; any
+// other State is shared. The opcode table, code, context and tracer are not
+// modified during execution and are shared.
+func (evm *EVM) Clone() *EVM {
+	c := *evm
+	c.stack = evm.stack.copy()
+	c.memory = slices.Clone(evm.memory)
+	if state, ok := evm.state.(*MemoryState); ok {
+		c.state = state.Copy()
+	}
+	c.transient = make(map[slotKey]*uint256.Int, len(evm.transient))
+	for slot, value := range evm.transient {
+		c.transient[slot] = value.Clone()
+	}
+	c.access = &accessList{
+		addresses: maps.Clone(evm.access.addresses),
+		slots:     maps.Clone(evm.access.slots),
+	}
+	c.originals = maps.Clone(evm.originals)
+	c.destructs = maps.Clone(evm.destructs)
+	c.journal = slices.Clone(evm.journal)
+	c.logs = slices.Clone(evm.logs)
+	c.returnData = slices.Clone(evm.returnData)
+	c.returnBuffer = slices.Clone(evm.returnBuffer)
+	return &c
+}
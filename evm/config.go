@@ -0,0 +1,48 @@
+package evm
+
+// Fork identifies a protocol upgrade. Forks are ordered, so a later fork
+// enables every opcode of the ones before it.
+type Fork int
+
+const (
+	Frontier Fork = iota
+	Homestead
+	Byzantium
+	Constantinople
+	Istanbul
+	Berlin
+	London
+	Shanghai
+	Cancun
+)
+
+var forkNames = [...]string{
+	Frontier:       "Frontier",
+	Homestead:      "Homestead",
+	Byzantium:      "Byzantium",
+	Constantinople: "Constantinople",
+	Istanbul:       "Istanbul",
+	Berlin:         "Berlin",
+	London:         "London",
+	Shanghai:       "Shanghai",
+	Cancun:         "Cancun",
+}
+
+func (f Fork) String() string {
+	if f < 0 || int(f) >= len(forkNames) {
+		return "Unknown"
+	}
+	return forkNames[f]
+}
+
+// Config selects the rules the EVM follows.
+type Config struct {
+	// Fork is the active protocol upgrade. Opcodes introduced after it fault
+	// as invalid.
+	Fork Fork
+	// Gas, if set, replaces the gas schedule Fork would use.
+	Gas *GasSchedule
+}
+
+// DefaultConfig follows the latest supported fork.
+var DefaultConfig = Config{Fork: Cancun}
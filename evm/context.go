@@ -0,0 +1,64 @@
+package evm
+
+import (
+	"math/big"
+
+	"github.com/holiman/uint256"
+)
+
+// Context carries the block, transaction and message environment that the
+// environmental opcodes read from. Unset big.Int fields read as zero, except
+// ChainID which defaults to 1 (mainnet).
+type Context struct {
+	BlockNumber *big.Int
+	Timestamp   *big.Int
+	Coinbase    [20]byte
+	GasLimit    uint64
+	PrevRandao  [32]byte
+	ChainID     *big.Int
+	BaseFee     *big.Int
+	GasPrice    *big.Int
+	BlobHashes  [][32]byte
+	BlobBaseFee *big.Int
+	Origin      [20]byte
+	Caller      [20]byte
+	Address     [20]byte
+	CallValue   *big.Int
+	CallData    []byte
+	// GetBlockHash returns the hash of block n, for BLOCKHASH. BLOCKHASH
+	// reads 0 if it is nil.
+	GetBlockHash func(n uint64) [32]byte
+	// AccessList lists the accounts and storage slots an EIP-2930
+	// transaction declares up front, which start out warm.
+	AccessList []AccessTuple
+}
+
+// AccessTuple is one entry of an EIP-2930 access list.
+type AccessTuple struct {
+	Address     [20]byte
+	StorageKeys []Hash
+}
+
+// wordFromBig converts x to a 256-bit word, reading nil as 0. Values that do
+// not fit are truncated to their low 256 bits.
+func wordFromBig(x *big.Int) *uint256.Int {
+	word := new(uint256.Int)
+	if x != nil {
+		word.SetFromBig(x)
+	}
+	return word
+}
+
+func addressToWord(addr [20]byte) *uint256.Int {
+	return new(uint256.Int).SetBytes(addr[:])
+}
+
+// getData returns size bytes of data starting at offset, zero-padded where the
+// range runs past the end of data.
+func getData(data []byte, offset *uint256.Int, size int) []byte {
+	out := make([]byte, size)
+	if offset.IsUint64() && offset.Uint64() < uint64(len(data)) {
+		copy(out, data[offset.Uint64():])
+	}
+	return out
+}
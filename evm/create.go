@@ -0,0 +1,145 @@
+package evm
+
+import (
+	"math"
+
+	"github.com/holiman/uint256"
+)
+
+// maxCodeSize is the largest contract that can be deployed (EIP-170).
+const maxCodeSize = 24576
+
+// maxInitCodeSize is the largest init code CREATE and CREATE2 accept from
+// Shanghai (EIP-3860).
+const maxInitCodeSize = 2 * maxCodeSize
+
+func (evm *EVM) opCreate(bytecode []byte) bool {
+	value := evm.pop()
+	offset := evm.pop()
+	length := evm.pop()
+	off, size, _ := toMemoryRange(&offset, &length)
+	if evm.config.Fork >= Shanghai && size > maxInitCodeSize {
+		return evm.fail(ErrInitCodeSize)
+	}
+	evm.expandMemory(off, size)
+	initCode := evm.readMemory(off, size)
+
+	sender := evm.ctx.Address
+	addr := createAddress(sender, evm.state.GetNonce(sender))
+	output, gasLeft, ok := evm.create(addr, &value, initCode)
+	return evm.finishCreate(addr, output, gasLeft, ok)
+}
+
+func (evm *EVM) opCreate2(bytecode []byte) bool {
+	value := evm.pop()
+	offset := evm.pop()
+	length := evm.pop()
+	salt := evm.pop()
+	off, size, _ := toMemoryRange(&offset, &length)
+	if evm.config.Fork >= Shanghai && size > maxInitCodeSize {
+		return evm.fail(ErrInitCodeSize)
+	}
+	evm.expandMemory(off, size)
+	initCode := evm.readMemory(off, size)
+
+	addr := create2Address(evm.ctx.Address, salt.Bytes32(), initCode)
+	output, gasLeft, ok := evm.create(addr, &value, initCode)
+	return evm.finishCreate(addr, output, gasLeft, ok)
+}
+
+// finishCreate pushes the address of a contract created by CREATE or CREATE2
+// after refunding the gas its init code left, or 0 if creation failed. Only a
+// failed creation leaves return data behind.
+func (evm *EVM) finishCreate(addr [20]byte, output []byte, gasLeft uint64, ok bool) bool {
+	evm.gas += gasLeft
+	if !ok {
+		evm.returnBuffer = output
+		return evm.push(new(uint256.Int))
+	}
+	evm.returnBuffer = nil
+	return evm.push(addressToWord(addr))
+}
+
+// create deploys a contract at addr by running initCode with value
+// transferred to it, forwarding all but one 64th of the remaining gas. The
+// creator's nonce is incremented even if creation fails. It returns the revert
+// data if the init code reverted, the gas it left after paying for the
+// deployed code, and whether creation succeeded.
+func (evm *EVM) create(addr [20]byte, value *uint256.Int, initCode []byte) ([]byte, uint64, bool) {
+	gas := callGas(evm.gas, math.MaxUint64)
+	evm.gas -= gas
+	if evm.depth >= maxCallDepth {
+		return nil, gas, false
+	}
+
+	sender := evm.ctx.Address
+	if evm.state.GetBalance(sender).Lt(value) {
+		return nil, gas, false
+	}
+	evm.setNonce(sender, evm.state.GetNonce(sender)+1)
+	// EIP-2929 warms the new address even if creation then fails.
+	evm.accessAddress(addr)
+	if evm.state.GetNonce(addr) != 0 || len(evm.state.GetCode(addr)) != 0 {
+		return nil, 0, false
+	}
+
+	snapshot := evm.snapshot()
+	evm.setNonce(addr, 1)
+	evm.transfer(sender, addr, value)
+	ctx := evm.ctx
+	ctx.Caller = sender
+	ctx.Address = addr
+	ctx.CallValue = value.ToBig()
+	ctx.CallData = nil
+	result := evm.runFrame(ctx, initCode, gas, false)
+	if result.Err != nil {
+		// As in callFrame, only the step limit leaves gas behind.
+		evm.revertTo(snapshot)
+		return nil, result.GasLeft, false
+	}
+	if result.Reverted {
+		evm.revertTo(snapshot)
+		return result.ReturnData, result.GasLeft, false
+	}
+
+	code := result.ReturnData
+	deposit := gasCodeDeposit * uint64(len(code))
+	if len(code) > maxCodeSize || result.GasLeft < deposit {
+		evm.revertTo(snapshot)
+		return nil, 0, false
+	}
+	evm.setCode(addr, code)
+	return nil, result.GasLeft - deposit, true
+}
+
+// createAddress returns the address of a contract created by CREATE:
+// keccak256(rlp([sender, nonce]))[12:].
+func createAddress(sender [20]byte, nonce uint64) [20]byte {
+	payload := append([]byte{0x80 + 20}, sender[:]...)
+	payload = append(payload, rlpUint(nonce)...)
+	hash := Keccak256([]byte{0xc0 + byte(len(payload))}, payload)
+	return [20]byte(hash[12:])
+}
+
+// create2Address returns the address of a contract created by CREATE2:
+// keccak256(0xff ++ sender ++ salt ++ keccak256(initCode))[12:].
+func create2Address(sender [20]byte, salt [32]byte, initCode []byte) [20]byte {
+	codeHash := Keccak256(initCode)
+	hash := Keccak256([]byte{0xff}, sender[:], salt[:], codeHash[:])
+	return [20]byte(hash[12:])
+}
+
+// rlpUint returns the RLP encoding of an unsigned integer.
+func rlpUint(x uint64) []byte {
+	if x == 0 {
+		return []byte{0x80}
+	}
+	if x < 0x80 {
+		return []byte{byte(x)}
+	}
+	var b []byte
+	for ; x > 0; x >>= 8 {
+		b = append([]byte{byte(x)}, b...)
+	}
+	return append([]byte{0x80 + byte(len(b))}, b...)
+}
@@ -0,0 +1,29 @@
+package evm
+
+import "testing"
+
+// TestFailedCreateWarmsAddress checks that the address of a CREATE whose init
+// code reverts is still warm afterwards.
+func TestFailedCreateWarmsAddress(t *testing.T) {
+	evm := NewEVM(WithGas(1000000))
+	addr := createAddress(evm.ctx.Address, 0)
+	// Init code PUSH1 0 PUSH1 0 REVERT, stored at memory 0 and run by CREATE.
+	code, err := Assemble(`
+	PUSH5 0x60006000fd
+	PUSH1 0
+	MSTORE
+	PUSH1 5   ; length
+	PUSH1 27  ; offset
+	PUSH1 0   ; value
+	CREATE
+	POP`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := evm.Execute(code); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if !evm.access.addresses[addr] {
+		t.Error("created address is cold after a reverted CREATE")
+	}
+}
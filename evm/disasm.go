@@ -0,0 +1,47 @@
+package evm
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// Instruction is a single decoded opcode. Immediate holds the hex-encoded
+// data of a PUSH and is empty for every other opcode.
+type Instruction struct {
+	PC        int
+	Op        byte
+	Name      string
+	Immediate string
+}
+
+func (ins Instruction) String() string {
+	switch {
+	case ins.Immediate != "":
+		return ins.Name + " " + ins.Immediate
+	case ins.Name == "INVALID":
+		return fmt.Sprintf("INVALID 0x%02x", ins.Op)
+	default:
+		return ins.Name
+	}
+}
+
+// Disassemble decodes bytecode into instructions, skipping over PUSH data so
+// that it is not mistaken for opcodes. A PUSH truncated by the end of the code
+// keeps whatever immediate bytes are present.
+func Disassemble(bytecode []byte) []Instruction {
+	var instructions []Instruction
+	for pc := 0; pc < len(bytecode); pc++ {
+		op := bytecode[pc]
+		ins := Instruction{PC: pc, Op: op, Name: OpName(uint64(op))}
+		if 0x60 <= op && op <= 0x7f {
+			end := pc + 1 + int(op-0x5f)
+			if end > len(bytecode) {
+				end = len(bytecode)
+			}
+			ins.Immediate = "0x" + hex.EncodeToString(bytecode[pc+1:end])
+			pc = end - 1
+		}
+		instructions = append(instructions, ins)
+	}
+	return instructions
+}
@@ -0,0 +1,48 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxEstimateGas bounds the search in EstimateGas when ctx.GasLimit is unset.
+const maxEstimateGas = 30_000_000
+
+// EstimateGas returns the least initial gas for which code runs under ctx
+// without running out of gas. Every attempt starts from a fresh EVM with empty
+// state. ctx.GasLimit, if set, is the most that will be tried. It fails if code
+// reverts or faults for any other reason even with the most gas.
+func EstimateGas(code []byte, ctx Context) (uint64, error) {
+	run := func(gas uint64) error {
+		result := NewEVM(WithGas(gas), WithContext(ctx)).Execute(code)
+		if result.Err == nil && result.Reverted {
+			return ErrExecutionReverted
+		}
+		return result.Err
+	}
+
+	hi := ctx.GasLimit
+	if hi == 0 {
+		hi = maxEstimateGas
+	}
+	if err := run(hi); err != nil {
+		if errors.Is(err, ErrOutOfGas) {
+			return 0, fmt.Errorf("gas required exceeds %d: %w", hi, err)
+		}
+		return 0, err
+	}
+	// Invariant: execution succeeds with hi gas but not with lo.
+	lo := uint64(0)
+	if run(lo) == nil {
+		return 0, nil
+	}
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if run(mid) == nil {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, nil
+}
@@ -0,0 +1,33 @@
+package evm
+
+import "testing"
+
+// FuzzExecute runs arbitrary bytecode with bounded gas and steps, checking
+// that execution always ends in a consistent result rather than a panic.
+func FuzzExecute(f *testing.F) {
+	f.Add(mustFromHex("0x600560050200"), uint64(1000)) // PUSH1 5 PUSH1 5 MUL STOP
+	f.Add([]byte{0x7f}, uint64(10))                    // truncated PUSH32
+	f.Add([]byte{0x01}, uint64(10))                    // stack underflow
+	f.Add([]byte{0x60, 3, 0x56}, uint64(100))          // jump out of the code
+	f.Add([]byte{0x5b, 0x60, 0, 0x56}, uint64(100000)) // infinite loop
+	// STATICCALL to modexp with a base length of 2^64-1.
+	f.Add(mustFromHex("0x67ffffffffffffffff600052600060006060600060055afa"), uint64(1000000))
+	f.Fuzz(func(t *testing.T, code []byte, gas uint64) {
+		gas %= 10000000
+		result := NewEVM(WithGas(gas), WithMaxSteps(10000)).Execute(code)
+		if result.GasUsed+result.GasLeft != gas {
+			t.Fatalf("gas used %d + left %d != %d", result.GasUsed, result.GasLeft, gas)
+		}
+		if result.Success != (result.Err == nil && !result.Reverted) {
+			t.Fatalf("success = %v with err %v, reverted %v", result.Success, result.Err, result.Reverted)
+		}
+	})
+}
+
+func mustFromHex(s string) []byte {
+	b, err := FromHex(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
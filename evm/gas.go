@@ -0,0 +1,290 @@
+package evm
+
+import (
+	"math"
+
+	"github.com/holiman/uint256"
+)
+
+// Static gas tiers from the yellow paper, plus the constants used by the
+// dynamic gas functions below that no fork has changed. The rest come from
+// the EVM's GasSchedule.
+const (
+	gasZero     = 0
+	gasJumpdest = 1
+	gasBase     = 2
+	gasVeryLow  = 3
+	gasLow      = 5
+	gasMid      = 8
+	gasHigh     = 10
+
+	gasCopyWord    = 3
+	gasLog         = 375
+	gasLogTopic    = 375
+	gasLogByte     = 8
+	gasCallStipend = 2300
+	gasCreate      = 32000
+	gasBlockhash   = 20
+	gasCodeDeposit = 200
+	gasWarmAccess  = 100
+
+	gasSelfdestructRefund = 24000
+	gasInitCodeWord       = 2
+)
+
+// gasExp charges per byte of the exponent on top of EXP's base cost.
+func gasExp(evm *EVM) uint64 {
+	exponent := evm.peek(1)
+	return evm.schedule.ExpByte * uint64(exponent.ByteLen())
+}
+
+// copyGas returns the per-word cost of copying or hashing size bytes, charged
+// by the copy opcodes, SHA3 and CREATE2 on top of memory expansion.
+func copyGas(size, perWord uint64) uint64 {
+	return perWord * ((size + 31) / 32)
+}
+
+// gasSha3 charges per word hashed plus any memory expansion.
+func gasSha3(evm *EVM) uint64 {
+	offset := evm.peek(0)
+	length := evm.peek(1)
+	memGas := evm.memoryExpansionGas(offset, length)
+	if memGas == math.MaxUint64 {
+		return memGas
+	}
+	return memGas + copyGas(length.Uint64(), evm.schedule.Sha3Word)
+}
+
+// gasMemoryWord charges memory expansion for a 32-byte access at the top-of-stack offset.
+func gasMemoryWord(evm *EVM) uint64 {
+	return evm.memoryExpansionGas(evm.peek(0), uint256.NewInt(32))
+}
+
+func gasMstore8(evm *EVM) uint64 {
+	return evm.memoryExpansionGas(evm.peek(0), uint256.NewInt(1))
+}
+
+// gasCopy charges 3 gas per word copied plus memory expansion for the copy
+// opcodes, which take the destination offset first and the length third.
+func gasCopy(evm *EVM) uint64 {
+	length := evm.peek(2)
+	memGas := evm.memoryExpansionGas(evm.peek(0), length)
+	if memGas == math.MaxUint64 {
+		return memGas
+	}
+	return memGas + copyGas(length.Uint64(), gasCopyWord)
+}
+
+// gasExtcodecopy charges 3 gas per word copied plus memory expansion for
+// EXTCODECOPY, which takes the address before the usual copy arguments.
+func gasExtcodecopy(evm *EVM) uint64 {
+	length := evm.peek(3)
+	memGas := evm.memoryExpansionGas(evm.peek(1), length)
+	if memGas == math.MaxUint64 {
+		return memGas
+	}
+	return memGas + copyGas(length.Uint64(), gasCopyWord)
+}
+
+// gasMcopy charges 3 gas per word copied plus memory expansion covering both
+// the source and destination ranges.
+func gasMcopy(evm *EVM) uint64 {
+	dest := evm.peek(0)
+	src := evm.peek(1)
+	length := evm.peek(2)
+	end := dest
+	if src.Gt(dest) {
+		end = src
+	}
+	memGas := evm.memoryExpansionGas(end, length)
+	if memGas == math.MaxUint64 {
+		return memGas
+	}
+	return memGas + copyGas(length.Uint64(), gasCopyWord)
+}
+
+// gasCall charges as gasCallcode does, plus the cost of creating the callee
+// if the call brings it into existence: from Byzantium (EIP-161) that is a
+// value transfer to an empty account, and before it any call to an account
+// that does not exist.
+func gasCall(evm *EVM) uint64 {
+	gas := gasCallcode(evm)
+	if gas == math.MaxUint64 {
+		return gas
+	}
+	to := evm.peek(1).Bytes20()
+	if evm.config.Fork >= Byzantium {
+		if !evm.peek(2).IsZero() && evm.isEmpty(to) {
+			gas += evm.schedule.NewAccount
+		}
+	} else if !evm.state.Exist(to) {
+		gas += evm.schedule.NewAccount
+	}
+	return gas
+}
+
+// gasCallcode charges memory expansion for a CALL or CALLCODE plus the value
+// transfer cost if it sends value. The gas forwarded to the callee is charged separately by opCall.
+func gasCallcode(evm *EVM) uint64 {
+	gas := callMemoryGas(evm, 3)
+	if gas != math.MaxUint64 && !evm.peek(2).IsZero() {
+		gas += evm.schedule.CallValue
+	}
+	return gas
+}
+
+// isEmpty reports whether the account at addr has no code, nonce or balance,
+// as defined by EIP-161.
+func (evm *EVM) isEmpty(addr [20]byte) bool {
+	return len(evm.state.GetCode(addr)) == 0 && evm.state.GetNonce(addr) == 0 && evm.state.GetBalance(addr).IsZero()
+}
+
+// gasCallNoValue charges memory expansion for STATICCALL and DELEGATECALL,
+// which take no value argument.
+func gasCallNoValue(evm *EVM) uint64 {
+	return callMemoryGas(evm, 2)
+}
+
+// callMemoryGas charges memory expansion covering both the argument and
+// return ranges of a CALL-family opcode, whose argument offset is the n-th
+// stack item and is followed by the argument length, return offset and return
+// length.
+func callMemoryGas(evm *EVM, n int) uint64 {
+	argsGas := evm.memoryExpansionGas(evm.peek(n), evm.peek(n+1))
+	retGas := evm.memoryExpansionGas(evm.peek(n+2), evm.peek(n+3))
+	if argsGas == math.MaxUint64 || retGas == math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return max(argsGas, retGas)
+}
+
+// gasCreateCode charges memory expansion for the init code of a CREATE,
+// plus 2 gas per word of it from Shanghai (EIP-3860).
+func gasCreateCode(evm *EVM) uint64 {
+	length := evm.peek(2)
+	memGas := evm.memoryExpansionGas(evm.peek(1), length)
+	if memGas == math.MaxUint64 {
+		return memGas
+	}
+	return memGas + evm.initCodeGas(length.Uint64())
+}
+
+// gasCreate2 charges memory expansion for the init code of a CREATE2 plus the
+// per-word SHA3 cost of hashing it into the address, and from Shanghai the
+// EIP-3860 init code cost.
+func gasCreate2(evm *EVM) uint64 {
+	length := evm.peek(2)
+	memGas := evm.memoryExpansionGas(evm.peek(1), length)
+	if memGas == math.MaxUint64 {
+		return memGas
+	}
+	return memGas + copyGas(length.Uint64(), evm.schedule.Sha3Word) + evm.initCodeGas(length.Uint64())
+}
+
+// initCodeGas returns the EIP-3860 per-word cost of size bytes of init code,
+// which is zero before Shanghai.
+func (evm *EVM) initCodeGas(size uint64) uint64 {
+	if evm.config.Fork < Shanghai {
+		return 0
+	}
+	return copyGas(size, gasInitCodeWord)
+}
+
+// gasSelfdestruct charges for sending a balance to an empty account (EIP-161),
+// plus the EIP-2929 cold surcharge from Berlin.
+func gasSelfdestruct(evm *EVM) uint64 {
+	beneficiary := evm.peek(0).Bytes20()
+	var gas uint64
+	if evm.config.Fork >= Berlin && evm.accessAddress(beneficiary) {
+		gas += evm.schedule.ColdAccount
+	}
+	// SELFDESTRUCT pays for a new account only from EIP-150, which every fork
+	// here that has it combines with EIP-161's notion of an empty account.
+	if evm.config.Fork >= Byzantium && evm.isEmpty(beneficiary) && !evm.state.GetBalance(evm.ctx.Address).IsZero() {
+		gas += evm.schedule.NewAccount
+	}
+	return gas
+}
+
+// gasMemoryRange charges memory expansion for the range given by the top two
+// stack items as offset and length.
+func gasMemoryRange(evm *EVM) uint64 {
+	return evm.memoryExpansionGas(evm.peek(0), evm.peek(1))
+}
+
+// gasLogData charges 8 gas per byte of log data plus memory expansion.
+func gasLogData(evm *EVM) uint64 {
+	length := evm.peek(1)
+	memGas := evm.memoryExpansionGas(evm.peek(0), length)
+	if memGas == math.MaxUint64 {
+		return memGas
+	}
+	return memGas + gasLogByte*length.Uint64()
+}
+
+// gasSstore prices SSTORE and accrues its refunds. Before Istanbul it charges
+// the set price for writing a zero slot and the reset price otherwise, with a
+// refund for clearing one. From Istanbul it uses EIP-2200 net gas metering,
+// which compares the new value against both the current value and the one the
+// slot held when the transaction started, with the warm and cold prices of
+// EIP-2929 from Berlin.
+func gasSstore(evm *EVM) uint64 {
+	key := Hash(evm.peek(0).Bytes32())
+	value := evm.peek(1)
+	current := evm.state.GetStorage(evm.ctx.Address, key)
+	s := &evm.schedule
+	if evm.config.Fork < Istanbul {
+		if current.IsZero() && !value.IsZero() {
+			return s.SstoreSet
+		}
+		if !current.IsZero() && value.IsZero() {
+			evm.addRefund(s.SstoreClearRefund)
+		}
+		return s.SstoreReset
+	}
+	// EIP-2200 refuses to run SSTORE on no more than a call stipend.
+	if evm.gas <= gasCallStipend {
+		return math.MaxUint64
+	}
+
+	var gas uint64
+	noopGas, resetGas, clearRefund := s.Sload, s.SstoreReset, s.SstoreClearRefund
+	if evm.config.Fork >= Berlin {
+		noopGas, resetGas = s.WarmAccess, s.SstoreReset-s.ColdSload
+		if evm.accessSlot(key) {
+			gas = s.ColdSload
+		}
+	}
+
+	original := evm.originalStorage(key, current)
+	if current.Eq(value) {
+		return gas + noopGas
+	}
+	if original.Eq(current) {
+		if original.IsZero() {
+			return gas + s.SstoreSet
+		}
+		if value.IsZero() {
+			evm.addRefund(clearRefund)
+		}
+		return gas + resetGas
+	}
+
+	// The slot was already written in this transaction, so the full price has
+	// been paid; adjust the refund for what this write undoes or clears.
+	if !original.IsZero() {
+		if current.IsZero() {
+			evm.subRefund(clearRefund)
+		} else if value.IsZero() {
+			evm.addRefund(clearRefund)
+		}
+	}
+	if original.Eq(value) {
+		if original.IsZero() {
+			evm.addRefund(s.SstoreSet - noopGas)
+		} else {
+			evm.addRefund(resetGas - noopGas)
+		}
+	}
+	return gas + noopGas
+}
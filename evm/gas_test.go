@@ -0,0 +1,21 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+// TestSelfdestructToEmptyAccount checks that SELFDESTRUCT sending a balance
+// to an account that exists but is empty pays the new-account charge.
+func TestSelfdestructToEmptyAccount(t *testing.T) {
+	beneficiary := [20]byte{19: 0x99}
+	state := NewMemoryState(nil)
+	evm := NewEVM(WithGas(100000), WithState(state), WithConfig(Config{Fork: Istanbul}))
+	state.SetBalance(evm.ctx.Address, uint256.NewInt(1))
+	state.SetBalance(beneficiary, new(uint256.Int)) // exists, but empty
+	result := evm.Execute([]byte{0x60, 0x99, 0xff})
+	if want := uint64(3 + 5000 + 25000); result.GasUsed != want {
+		t.Errorf("gas used = %d, want %d", result.GasUsed, want)
+	}
+}
@@ -0,0 +1,24 @@
+package evm
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// FromHex decodes a hex string such as "0x6005600502" into bytecode. The "0x"
+// prefix is optional.
+func FromHex(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	return hex.DecodeString(s)
+}
+
+// ExecuteHex decodes s with FromHex and executes the resulting bytecode.
+func (evm *EVM) ExecuteHex(s string) (ExecutionResult, error) {
+	bytecode, err := FromHex(s)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	return evm.Execute(bytecode), nil
+}
@@ -0,0 +1,80 @@
+package evm
+
+import (
+	"math/big"
+
+	"github.com/holiman/uint256"
+)
+
+// Stack returns a copy of the stack, bottom first.
+func (evm *EVM) Stack() []*big.Int {
+	stack := make([]*big.Int, evm.stack.Len())
+	for i, v := range evm.stack.data {
+		stack[i] = v.ToBig()
+	}
+	return stack
+}
+
+// DumpStack renders the stack in decimal, bottom first. When signed is true,
+// words are read as two's-complement, so the all-ones word renders as "-1".
+func (evm *EVM) DumpStack(signed bool) []string {
+	dump := make([]string, evm.stack.Len())
+	for i, v := range evm.stack.data {
+		if signed && v.Sign() < 0 {
+			var abs uint256.Int
+			abs.Neg(&v)
+			dump[i] = "-" + abs.Dec()
+		} else {
+			dump[i] = v.Dec()
+		}
+	}
+	return dump
+}
+
+// Memory returns a copy of memory.
+func (evm *EVM) Memory() []byte {
+	return append([]byte(nil), evm.memory...)
+}
+
+// MemoryAt returns a copy of size bytes of memory starting at offset, reading
+// zeros past the end. It returns nil if either argument is negative.
+func (evm *EVM) MemoryAt(offset, size int) []byte {
+	if offset < 0 || size < 0 {
+		return nil
+	}
+	return evm.readMemory(offset, size)
+}
+
+// StorageAt returns the value the executing contract stores under key, or 0
+// if it was never set.
+func (evm *EVM) StorageAt(key *big.Int) *big.Int {
+	var word uint256.Int
+	word.SetFromBig(key)
+	return evm.state.GetStorage(evm.ctx.Address, Hash(word.Bytes32())).ToBig()
+}
+
+// State returns the world state the EVM runs against.
+func (evm *EVM) State() State {
+	return evm.state
+}
+
+// SelfDestructed reports whether the contract at addr has executed
+// SELFDESTRUCT and is due to be deleted.
+func (evm *EVM) SelfDestructed(addr [20]byte) bool {
+	return evm.destructs[addr]
+}
+
+// Logs returns the logs emitted so far.
+func (evm *EVM) Logs() []Log {
+	return append([]Log(nil), evm.logs...)
+}
+
+// PC returns the offset of the next instruction to execute.
+func (evm *EVM) PC() int {
+	return evm.pc
+}
+
+// Gas returns the gas remaining.
+func (evm *EVM) Gas() uint64 {
+	return evm.gas
+}
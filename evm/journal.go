@@ -0,0 +1,217 @@
+package evm
+
+import "github.com/holiman/uint256"
+
+// journalEntry is a reversible change to the EVM's state.
+type journalEntry interface {
+	revert(evm *EVM)
+}
+
+// storageChange records the value a slot held before an SSTORE so that it can
+// be restored.
+type storageChange struct {
+	addr [20]byte
+	key  Hash
+	prev *uint256.Int
+}
+
+func (c storageChange) revert(evm *EVM) {
+	evm.state.SetStorage(c.addr, c.key, c.prev)
+}
+
+// balanceChange records an account's balance before a value transfer.
+type balanceChange struct {
+	addr [20]byte
+	prev *uint256.Int
+}
+
+func (c balanceChange) revert(evm *EVM) {
+	evm.state.SetBalance(c.addr, c.prev)
+}
+
+// codeChange records an account's code before a contract is deployed there.
+type codeChange struct {
+	addr [20]byte
+	prev []byte
+}
+
+func (c codeChange) revert(evm *EVM) {
+	evm.state.SetCode(c.addr, c.prev)
+}
+
+// nonceChange records an account's nonce before it was incremented.
+type nonceChange struct {
+	addr [20]byte
+	prev uint64
+}
+
+func (c nonceChange) revert(evm *EVM) {
+	evm.state.SetNonce(c.addr, c.prev)
+}
+
+// slotKey identifies a storage slot of a particular contract.
+type slotKey struct {
+	addr [20]byte
+	key  Hash
+}
+
+// transientChange is the storageChange counterpart for transient storage.
+// prev is nil if the slot was unset.
+type transientChange struct {
+	key  slotKey
+	prev *uint256.Int
+}
+
+func (c transientChange) revert(evm *EVM) {
+	if c.prev == nil {
+		delete(evm.transient, c.key)
+	} else {
+		evm.transient[c.key] = c.prev
+	}
+}
+
+// refundChange records the refund counter before SSTORE adjusted it.
+type refundChange struct {
+	prev uint64
+}
+
+func (c refundChange) revert(evm *EVM) {
+	evm.refund = c.prev
+}
+
+// destructChange records that a contract was marked for deletion.
+type destructChange struct {
+	addr [20]byte
+}
+
+func (c destructChange) revert(evm *EVM) {
+	delete(evm.destructs, c.addr)
+}
+
+// accountCreation records that an account was written for the first time.
+// Reverting it deletes the account if the state supports that.
+type accountCreation struct {
+	addr [20]byte
+}
+
+func (c accountCreation) revert(evm *EVM) {
+	if state, ok := evm.state.(AccountDeleter); ok {
+		state.DeleteAccount(c.addr)
+	}
+}
+
+// logChange records that a log was emitted.
+type logChange struct{}
+
+func (logChange) revert(evm *EVM) {
+	evm.logs = evm.logs[:len(evm.logs)-1]
+}
+
+// journalCreation journals the creation of addr if it does not exist yet, so
+// that reverting the write that creates it removes it again.
+func (evm *EVM) journalCreation(addr [20]byte) {
+	if !evm.state.Exist(addr) {
+		evm.journal = append(evm.journal, accountCreation{addr: addr})
+	}
+}
+
+// setStorage writes a storage slot of the executing contract, journaling the
+// previous value.
+func (evm *EVM) setStorage(key Hash, value *uint256.Int) {
+	addr := evm.ctx.Address
+	evm.journalCreation(addr)
+	evm.journal = append(evm.journal, storageChange{addr: addr, key: key, prev: evm.state.GetStorage(addr, key)})
+	evm.state.SetStorage(addr, key, value)
+}
+
+// setBalance sets an account's balance, journaling the previous value.
+func (evm *EVM) setBalance(addr [20]byte, balance *uint256.Int) {
+	evm.journalCreation(addr)
+	evm.journal = append(evm.journal, balanceChange{addr: addr, prev: evm.state.GetBalance(addr)})
+	evm.state.SetBalance(addr, balance)
+}
+
+// setCode deploys code at addr, journaling the previous code.
+func (evm *EVM) setCode(addr [20]byte, code []byte) {
+	evm.journalCreation(addr)
+	evm.journal = append(evm.journal, codeChange{addr: addr, prev: evm.state.GetCode(addr)})
+	evm.state.SetCode(addr, code)
+}
+
+// setNonce sets an account's nonce, journaling the previous value.
+func (evm *EVM) setNonce(addr [20]byte, nonce uint64) {
+	evm.journalCreation(addr)
+	evm.journal = append(evm.journal, nonceChange{addr: addr, prev: evm.state.GetNonce(addr)})
+	evm.state.SetNonce(addr, nonce)
+}
+
+// addRefund and subRefund adjust the gas refund counter, journaling the
+// previous value.
+func (evm *EVM) addRefund(gas uint64) {
+	evm.journal = append(evm.journal, refundChange{prev: evm.refund})
+	evm.refund += gas
+}
+
+func (evm *EVM) subRefund(gas uint64) {
+	evm.journal = append(evm.journal, refundChange{prev: evm.refund})
+	evm.refund -= gas
+}
+
+// destruct marks a contract for deletion, journaling the mark.
+func (evm *EVM) destruct(addr [20]byte) {
+	if evm.destructs[addr] {
+		return
+	}
+	evm.journal = append(evm.journal, destructChange{addr: addr})
+	evm.destructs[addr] = true
+}
+
+// originalStorage returns the value a slot of the executing contract held when
+// the transaction started, given its current value. That is the current value
+// until the slot is first written.
+func (evm *EVM) originalStorage(key Hash, current *uint256.Int) *uint256.Int {
+	slot := slotKey{addr: evm.ctx.Address, key: key}
+	original, ok := evm.originals[slot]
+	if !ok {
+		original = *current
+		evm.originals[slot] = original
+	}
+	return &original
+}
+
+// setTransient writes a transient storage slot of the executing contract,
+// journaling the previous value.
+func (evm *EVM) setTransient(key Hash, value *uint256.Int) {
+	tk := slotKey{addr: evm.ctx.Address, key: key}
+	evm.journal = append(evm.journal, transientChange{key: tk, prev: evm.transient[tk]})
+	evm.transient[tk] = value.Clone()
+}
+
+// ClearTransient discards all transient storage. Call it between
+// transactions, since transient storage only lives for one.
+func (evm *EVM) ClearTransient() {
+	for key := range evm.transient {
+		delete(evm.transient, key)
+	}
+}
+
+// addLog records an emitted log, journaling it so that it is dropped on revert.
+func (evm *EVM) addLog(log Log) {
+	evm.journal = append(evm.journal, logChange{})
+	evm.logs = append(evm.logs, log)
+}
+
+// snapshot returns an id identifying the current state, to be passed to
+// revertTo. Snapshots nest: reverting to an earlier id also undoes every later
+// snapshot.
+func (evm *EVM) snapshot() int {
+	return len(evm.journal)
+}
+
+// revertTo undoes every state change and log made since snapshot id was taken.
+func (evm *EVM) revertTo(id int) {
+	for i := len(evm.journal) - 1; i >= id; i-- {
+		evm.journal[i].revert(evm)
+	}
+	evm.journal = evm.journal[:id]
+}
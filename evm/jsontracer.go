@@ -0,0 +1,69 @@
+package evm
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// JSONTracer writes one JSON object per instruction in the struct-log format
+// of geth's "evm --json". Stack entries are 32-byte hex words.
+type JSONTracer struct {
+	w   io.Writer
+	err error
+}
+
+// NewJSONTracer returns a tracer that writes to w.
+func NewJSONTracer(w io.Writer) *JSONTracer {
+	return &JSONTracer{w: w}
+}
+
+type structLog struct {
+	PC      int      `json:"pc"`
+	Op      string   `json:"op"`
+	Gas     string   `json:"gas"`
+	GasCost string   `json:"gasCost"`
+	Memory  string   `json:"memory"`
+	MemSize int      `json:"memSize"`
+	Stack   []string `json:"stack"`
+	Depth   int      `json:"depth"`
+	Refund  uint64   `json:"refund"`
+}
+
+// CaptureState traces an instruction whose cost and depth are not known.
+func (t *JSONTracer) CaptureState(pc int, op uint64, gas uint64, stack []*big.Int, memory []byte) {
+	t.CaptureStep(pc, op, gas, 0, 0, 1, stack, memory)
+}
+
+// CaptureStep writes the line for one instruction.
+func (t *JSONTracer) CaptureStep(pc int, op uint64, gas, cost, refund uint64, depth int, stack []*big.Int, memory []byte) {
+	if t.err != nil {
+		return
+	}
+	entry := structLog{
+		PC:      pc,
+		Op:      OpName(op),
+		Gas:     fmt.Sprintf("0x%x", gas),
+		GasCost: fmt.Sprintf("0x%x", cost),
+		Memory:  "0x" + hex.EncodeToString(memory),
+		MemSize: len(memory),
+		Stack:   make([]string, len(stack)),
+		Depth:   depth,
+		Refund:  refund,
+	}
+	for i, v := range stack {
+		entry.Stack[i] = fmt.Sprintf("0x%064x", v)
+	}
+	line, err := json.Marshal(entry)
+	if err == nil {
+		_, err = t.w.Write(append(line, '\n'))
+	}
+	t.err = err
+}
+
+// Err returns the first error encountered writing the trace.
+func (t *JSONTracer) Err() error {
+	return t.err
+}
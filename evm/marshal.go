@@ -0,0 +1,117 @@
+package evm
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/holiman/uint256"
+)
+
+// machineJSON is the encoding of an EVM used by MarshalJSON. Words and byte
+// strings are 0x-prefixed hex.
+type machineJSON struct {
+	Address    string            `json:"address"`
+	Code       string            `json:"code"`
+	PC         int               `json:"pc"`
+	Gas        uint64            `json:"gas"`
+	Stack      []string          `json:"stack"`
+	Memory     string            `json:"memory"`
+	Storage    map[string]string `json:"storage"`
+	Halted     bool              `json:"halted"`
+	ReturnData string            `json:"returnData"`
+}
+
+// MarshalJSON encodes the machine: the executing contract's address, code and
+// storage, along with the pc, gas, stack, memory, whether it has halted and
+// its return data. Storage can only be enumerated from a MemoryState. The
+// fault or revert that halted a machine is not encoded.
+func (evm *EVM) MarshalJSON() ([]byte, error) {
+	state, ok := evm.state.(*MemoryState)
+	if !ok {
+		return nil, fmt.Errorf("cannot enumerate the storage of %T", evm.state)
+	}
+	m := machineJSON{
+		Address:    "0x" + hex.EncodeToString(evm.ctx.Address[:]),
+		Code:       "0x" + hex.EncodeToString(evm.code),
+		PC:         evm.pc,
+		Gas:        evm.gas,
+		Stack:      make([]string, evm.stack.Len()),
+		Memory:     "0x" + hex.EncodeToString(evm.memory),
+		Storage:    make(map[string]string),
+		Halted:     evm.halted,
+		ReturnData: "0x" + hex.EncodeToString(evm.returnData),
+	}
+	for i, v := range evm.stack.data {
+		m.Stack[i] = v.Hex()
+	}
+	if acct, ok := state.accounts[evm.ctx.Address]; ok {
+		for key, value := range acct.storage {
+			m.Storage["0x"+hex.EncodeToString(key[:])] = value.Hex()
+		}
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON restores a machine encoded by MarshalJSON, so that it resumes
+// from the same pc. The storage is written to the EVM's state under the
+// decoded address, which replaces the one in its context.
+func (evm *EVM) UnmarshalJSON(data []byte) error {
+	var m machineJSON
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	address, err := FromHex(m.Address)
+	if err != nil || len(address) != 20 {
+		return fmt.Errorf("invalid address %q", m.Address)
+	}
+	code, err := FromHex(m.Code)
+	if err != nil {
+		return fmt.Errorf("invalid code: %w", err)
+	}
+	if m.PC < 0 || m.PC > len(code) {
+		return fmt.Errorf("pc %d outside code of length %d", m.PC, len(code))
+	}
+	memory, err := FromHex(m.Memory)
+	if err != nil {
+		return fmt.Errorf("invalid memory: %w", err)
+	}
+	returnData, err := FromHex(m.ReturnData)
+	if err != nil {
+		return fmt.Errorf("invalid return data: %w", err)
+	}
+	if len(m.Stack) > stackLimit {
+		return ErrStackOverflow
+	}
+	stack := make([]uint256.Int, len(m.Stack), stackLimit)
+	for i, s := range m.Stack {
+		if err := stack[i].SetFromHex(s); err != nil {
+			return fmt.Errorf("invalid stack item %q: %w", s, err)
+		}
+	}
+	storage := make(map[Hash]*uint256.Int, len(m.Storage))
+	for k, v := range m.Storage {
+		key, err := FromHex(k)
+		if err != nil || len(key) != 32 {
+			return fmt.Errorf("invalid storage key %q", k)
+		}
+		value, err := uint256.FromHex(v)
+		if err != nil {
+			return fmt.Errorf("invalid storage value %q: %w", v, err)
+		}
+		storage[Hash(key)] = value
+	}
+
+	copy(evm.ctx.Address[:], address)
+	evm.SetCode(code)
+	evm.pc = m.PC
+	evm.gas = m.Gas
+	evm.stack = &Stack{data: stack}
+	evm.memory = memory
+	evm.halted = m.Halted
+	evm.returnData = returnData
+	for key, value := range storage {
+		evm.state.SetStorage(evm.ctx.Address, key, value)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package evm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestMarshalHalted checks that a machine encoded after STOP decodes as
+// halted, so the bytes after the STOP are not run.
+func TestMarshalHalted(t *testing.T) {
+	evm := NewEVM(WithGas(1000))
+	evm.Execute([]byte{0x60, 1, 0x00, 0x60, 2})
+	data, err := json.Marshal(evm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewEVM()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := restored.Step(); !done || err != nil {
+		t.Fatalf("Step = %v, %v; want halted", done, err)
+	}
+	if stack := restored.Stack(); len(stack) != 1 || stack[0].Int64() != 1 {
+		t.Fatalf("stack = %v, want [1]", stack)
+	}
+}
+
+func TestUnmarshalInvalidPC(t *testing.T) {
+	for _, pc := range []string{"-1", "3"} {
+		data := `{"address":"0x0000000000000000000000000000000000000000","code":"0x6001","pc":` + pc + `,"memory":"0x","returnData":"0x"}`
+		err := json.Unmarshal([]byte(data), NewEVM())
+		if err == nil || !strings.Contains(err.Error(), "outside code") {
+			t.Errorf("pc %s: err = %v, want an out-of-range error", pc, err)
+		}
+	}
+}
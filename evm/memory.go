@@ -0,0 +1,69 @@
+package evm
+
+import (
+	"math"
+
+	"github.com/holiman/uint256"
+)
+
+// maxMemory bounds addressable memory; anything larger could never be paid for.
+const maxMemory = 1 << 32
+
+// memoryGasCost returns the total gas charged for memory of the given size in
+// bytes: a price per word plus a quadratic term. Callers charge the difference
+// between the cost of the new and the current size.
+func (s *GasSchedule) memoryGasCost(size int) uint64 {
+	words := uint64(size+31) / 32
+	return s.Memory*words + words*words/s.MemoryQuadDivisor
+}
+
+// toMemoryRange converts an offset/length pair popped from the stack into ints,
+// reporting false if the range is too large to address.
+func toMemoryRange(offset, length *uint256.Int) (int, int, bool) {
+	if length.IsZero() {
+		return 0, 0, true
+	}
+	if !offset.IsUint64() || !length.IsUint64() || offset.Uint64() > maxMemory || length.Uint64() > maxMemory {
+		return 0, 0, false
+	}
+	return int(offset.Uint64()), int(length.Uint64()), true
+}
+
+// memoryExpansionGas returns the gas needed to grow memory so that it covers
+// the given range. Only the growth beyond the current size is charged.
+func (evm *EVM) memoryExpansionGas(offset, length *uint256.Int) uint64 {
+	off, size, ok := toMemoryRange(offset, length)
+	if !ok {
+		return math.MaxUint64
+	}
+	if size == 0 || off+size <= len(evm.memory) {
+		return 0
+	}
+	return evm.schedule.memoryGasCost(off+size) - evm.schedule.memoryGasCost(len(evm.memory))
+}
+
+// readMemory returns a copy of size bytes of memory starting at offset. Bytes
+// past the end of memory read as zero; memory itself is not expanded.
+func (evm *EVM) readMemory(offset, size int) []byte {
+	out := make([]byte, size)
+	if offset < len(evm.memory) {
+		copy(out, evm.memory[offset:])
+	}
+	return out
+}
+
+// writeMemory copies data into memory at offset, expanding memory to cover it.
+func (evm *EVM) writeMemory(offset int, data []byte) {
+	evm.expandMemory(offset, len(data))
+	copy(evm.memory[offset:], data)
+}
+
+// expandMemory zero-fills memory so that it covers [offset, offset+size).
+// Memory grows a word at a time, so its length is always a multiple of 32.
+func (evm *EVM) expandMemory(offset, size int) {
+	if size == 0 || offset+size <= len(evm.memory) {
+		return
+	}
+	end := (offset + size + 31) / 32 * 32
+	evm.memory = append(evm.memory, make([]byte, end-len(evm.memory))...)
+}
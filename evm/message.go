@@ -0,0 +1,49 @@
+package evm
+
+import "math/big"
+
+// Message is a top-level call into a contract, as sent by a transaction.
+type Message struct {
+	Caller [20]byte
+	To     [20]byte
+	Value  *big.Int
+	Gas    uint64
+	Data   []byte
+}
+
+// Call executes the code deployed at msg.To as a message from msg.Caller,
+// transferring msg.Value first. The rest of the context, such as the block
+// fields, is kept; the caller also becomes the origin. As for a transaction,
+// the caller's nonce is incremented whatever the outcome, while every other
+// state change, including the transfer, is rolled back if the call reverts or
+// faults. Each Call starts a new transaction: the refund counter, access
+// list, transient storage and other per-transaction records are cleared,
+// while the state is kept.
+func (evm *EVM) Call(msg Message) ExecutionResult {
+	evm.ctx.Origin = msg.Caller
+	evm.ctx.Caller = msg.Caller
+	evm.ctx.Address = msg.To
+	evm.ctx.CallValue = msg.Value
+	evm.ctx.CallData = msg.Data
+	evm.gas = msg.Gas
+	evm.refund = 0
+	clear(evm.originals)
+	clear(evm.transient)
+	clear(evm.destructs)
+	evm.journal = nil
+	evm.access = newAccessList()
+	if evm.config.Fork >= Berlin {
+		evm.warmAccessList()
+	}
+
+	evm.setNonce(msg.Caller, evm.state.GetNonce(msg.Caller)+1)
+	snapshot := evm.snapshot()
+	if !evm.transfer(msg.Caller, msg.To, wordFromBig(msg.Value)) {
+		return ExecutionResult{GasLeft: msg.Gas, Err: ErrInsufficientBalance}
+	}
+	result := evm.Execute(evm.state.GetCode(msg.To))
+	if !result.Success {
+		evm.revertTo(snapshot)
+	}
+	return result
+}
@@ -1,143 +1,1038 @@
-package main
+package evm
 
 import (
+	"errors"
 	"fmt"
-	"math/big"
+	"math"
+
+	"github.com/holiman/uint256"
+)
+
+var (
+	ErrOutOfGas              = errors.New("out of gas")
+	ErrInvalidOpcode         = errors.New("invalid opcode")
+	ErrInvalidJump           = errors.New("invalid jump destination")
+	ErrStackUnderflow        = errors.New("stack underflow")
+	ErrStackOverflow         = errors.New("stack overflow")
+	ErrStepLimit             = errors.New("step limit reached")
+	ErrWriteProtection       = errors.New("write protection")
+	ErrReturnDataOutOfBounds = errors.New("return data out of bounds")
+	ErrExecutionReverted     = errors.New("execution reverted")
+	ErrInsufficientBalance   = errors.New("insufficient balance for transfer")
+	ErrInitCodeSize          = errors.New("max initcode size exceeded")
 )
 
+// OpError is a fault together with the instruction that raised it. It unwraps
+// to the underlying error, so errors.Is matches the sentinels above.
+type OpError struct {
+	PC  int
+	Op  uint64
+	Err error
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("%v: %s (0x%02x) at pc=%d", e.Err, OpName(e.Op), e.Op, e.PC)
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
 type EVM struct {
-	stack   []*big.Int
-	memory  []byte
-	storage map[uint64]*big.Int
-	pc      int
-	gas     int
-	opcodes map[uint64]opcode
+	stack      *Stack
+	memory     []byte
+	state      State
+	transient  map[slotKey]*uint256.Int
+	access     *accessList
+	originals  map[slotKey]uint256.Int
+	destructs  map[[20]byte]bool
+	refund     uint64
+	journal    []journalEntry
+	logs       []Log
+	pc         int
+	gas        uint64
+	opcodes    map[uint64]opcode
+	code       []byte
+	jumpdests  map[int]bool
+	halted     bool
+	returnData []byte
+	// returnBuffer holds the output of the most recent call or failed create,
+	// as read by RETURNDATASIZE and RETURNDATACOPY.
+	returnBuffer []byte
+	reverted     bool
+	err          error
+	ctx          Context
+	config       Config
+	schedule     GasSchedule
+	tracer       Tracer
+	readOnly     bool
+	steps        int
+	// stepBase is the number of steps taken by the frames above this one
+	// before it started, which count towards MaxSteps.
+	stepBase int
+	// depth is the number of frames above this one; the outermost is 0.
+	depth int
+	// opPC and op locate the instruction being executed, for OpError.
+	opPC int
+	op   uint64
+	// callerState is set when the state was supplied with WithState, so
+	// that Reset keeps it.
+	callerState bool
+	// strictStack makes a successful halt with items left on the stack
+	// produce a warning.
+	strictStack bool
+
+	// MaxSteps aborts execution with ErrStepLimit after this many
+	// instructions. Zero means no limit.
+	MaxSteps int
+}
+
+// Log is an event emitted by one of the LOG0-LOG4 opcodes.
+type Log struct {
+	Address [20]byte
+	Topics  [][32]byte
+	Data    []byte
+}
+
+type ExecutionResult struct {
+	// Success reports that execution neither reverted nor faulted.
+	Success    bool
+	ReturnData []byte
+	// Logs are the logs emitted by this execution. They are empty unless it
+	// succeeded.
+	Logs    []Log
+	GasUsed uint64
+	GasLeft uint64
+	// GasRefund is the gas SSTORE has earned back, capped at a fifth of GasUsed
+	// (half before London). It is not included in GasUsed or GasLeft.
+	GasRefund uint64
+	Reverted  bool
+	Err       error
+	// Warnings flags legal but suspicious outcomes. It is only filled in by
+	// an EVM built with WithStrictStack.
+	Warnings []string
 }
 
 type opcode struct {
-	fn      func(*EVM, []byte) bool
-	gasCost int
+	fn         func(*EVM, []byte) bool
+	gasCost    uint64
+	dynamicGas func(*EVM) uint64
+	minStack   int
+	// fork is the upgrade that introduced the opcode; it is invalid before then.
+	fork Fork
+	// writes marks opcodes that modify state, which fault in a read-only frame.
+	writes bool
 }
 
-func NewEVM(initialGas int) *EVM {
+func NewEVM(opts ...Option) *EVM {
 	evm := &EVM{
-		stack:   []*big.Int{},
-		memory:  []byte{},
-		storage: make(map[uint64]*big.Int),
-		pc:      0,
-		gas:     initialGas,
+		stack:     NewStack(),
+		memory:    []byte{},
+		state:     NewMemoryState(nil),
+		transient: make(map[slotKey]*uint256.Int),
+		originals: make(map[slotKey]uint256.Int),
+		destructs: make(map[[20]byte]bool),
+		pc:        0,
+		gas:       DefaultGas,
+		config:    DefaultConfig,
 		opcodes: map[uint64]opcode{
-			0x00: {(*EVM).opStop, 0},
-			0x01: {(*EVM).opAdd, 3},
-			0x02: {(*EVM).opMul, 5},
-			0x03: {(*EVM).opSub, 3},
-			0x04: {(*EVM).opDiv, 5},
-			0x60: {(*EVM).opPush1, 3},
+			0x00: {fn: (*EVM).opStop, gasCost: gasZero},
+			0x01: {fn: (*EVM).opAdd, gasCost: gasVeryLow, minStack: 2},
+			0x02: {fn: (*EVM).opMul, gasCost: gasLow, minStack: 2},
+			0x03: {fn: (*EVM).opSub, gasCost: gasVeryLow, minStack: 2},
+			0x04: {fn: (*EVM).opDiv, gasCost: gasLow, minStack: 2},
+			0x05: {fn: (*EVM).opSdiv, gasCost: gasLow, minStack: 2},
+			0x06: {fn: (*EVM).opMod, gasCost: gasLow, minStack: 2},
+			0x07: {fn: (*EVM).opSmod, gasCost: gasLow, minStack: 2},
+			0x08: {fn: (*EVM).opAddmod, gasCost: gasMid, minStack: 3},
+			0x09: {fn: (*EVM).opMulmod, gasCost: gasMid, minStack: 3},
+			0x0a: {fn: (*EVM).opExp, gasCost: gasHigh, dynamicGas: gasExp, minStack: 2},
+			0x0b: {fn: (*EVM).opSignextend, gasCost: gasLow, minStack: 2},
+			0x10: {fn: (*EVM).opLt, gasCost: gasVeryLow, minStack: 2},
+			0x11: {fn: (*EVM).opGt, gasCost: gasVeryLow, minStack: 2},
+			0x12: {fn: (*EVM).opSlt, gasCost: gasVeryLow, minStack: 2},
+			0x13: {fn: (*EVM).opSgt, gasCost: gasVeryLow, minStack: 2},
+			0x14: {fn: (*EVM).opEq, gasCost: gasVeryLow, minStack: 2},
+			0x15: {fn: (*EVM).opIszero, gasCost: gasVeryLow, minStack: 1},
+			0x16: {fn: (*EVM).opAnd, gasCost: gasVeryLow, minStack: 2},
+			0x17: {fn: (*EVM).opOr, gasCost: gasVeryLow, minStack: 2},
+			0x18: {fn: (*EVM).opXor, gasCost: gasVeryLow, minStack: 2},
+			0x19: {fn: (*EVM).opNot, gasCost: gasVeryLow, minStack: 1},
+			0x1a: {fn: (*EVM).opByte, gasCost: gasVeryLow, minStack: 2},
+			0x1b: {fn: (*EVM).opShl, gasCost: gasVeryLow, minStack: 2, fork: Constantinople},
+			0x1c: {fn: (*EVM).opShr, gasCost: gasVeryLow, minStack: 2, fork: Constantinople},
+			0x1d: {fn: (*EVM).opSar, gasCost: gasVeryLow, minStack: 2, fork: Constantinople},
+			0x20: {fn: (*EVM).opSha3, dynamicGas: gasSha3, minStack: 2},
+			0x30: {fn: (*EVM).opAddress, gasCost: gasBase},
+			0x31: {fn: (*EVM).opBalance, minStack: 1},
+			0x32: {fn: (*EVM).opOrigin, gasCost: gasBase},
+			0x33: {fn: (*EVM).opCaller, gasCost: gasBase},
+			0x34: {fn: (*EVM).opCallvalue, gasCost: gasBase},
+			0x35: {fn: (*EVM).opCalldataload, gasCost: gasVeryLow, minStack: 1},
+			0x36: {fn: (*EVM).opCalldatasize, gasCost: gasBase},
+			0x37: {fn: (*EVM).opCalldatacopy, gasCost: gasVeryLow, dynamicGas: gasCopy, minStack: 3},
+			0x38: {fn: (*EVM).opCodesize, gasCost: gasBase},
+			0x39: {fn: (*EVM).opCodecopy, gasCost: gasVeryLow, dynamicGas: gasCopy, minStack: 3},
+			0x3a: {fn: (*EVM).opGasprice, gasCost: gasBase},
+			0x3b: {fn: (*EVM).opExtcodesize, minStack: 1},
+			0x3c: {fn: (*EVM).opExtcodecopy, dynamicGas: gasExtcodecopy, minStack: 4},
+			0x3d: {fn: (*EVM).opReturndatasize, gasCost: gasBase, fork: Byzantium},
+			0x3e: {fn: (*EVM).opReturndatacopy, gasCost: gasVeryLow, dynamicGas: gasCopy, minStack: 3, fork: Byzantium},
+			0x3f: {fn: (*EVM).opExtcodehash, minStack: 1, fork: Constantinople},
+			0x40: {fn: (*EVM).opBlockhash, gasCost: gasBlockhash, minStack: 1},
+			0x41: {fn: (*EVM).opCoinbase, gasCost: gasBase},
+			0x42: {fn: (*EVM).opTimestamp, gasCost: gasBase},
+			0x43: {fn: (*EVM).opNumber, gasCost: gasBase},
+			0x44: {fn: (*EVM).opDifficulty, gasCost: gasBase},
+			0x45: {fn: (*EVM).opGaslimit, gasCost: gasBase},
+			0x46: {fn: (*EVM).opChainid, gasCost: gasBase, fork: Istanbul},
+			0x47: {fn: (*EVM).opSelfbalance, gasCost: gasLow, fork: Istanbul},
+			0x48: {fn: (*EVM).opBasefee, gasCost: gasBase, fork: London},
+			0x49: {fn: (*EVM).opBlobhash, gasCost: gasVeryLow, minStack: 1, fork: Cancun},
+			0x4a: {fn: (*EVM).opBlobbasefee, gasCost: gasBase, fork: Cancun},
+			0x50: {fn: (*EVM).opPop, gasCost: gasBase, minStack: 1},
+			0x51: {fn: (*EVM).opMload, gasCost: gasVeryLow, dynamicGas: gasMemoryWord, minStack: 1},
+			0x52: {fn: (*EVM).opMstore, gasCost: gasVeryLow, dynamicGas: gasMemoryWord, minStack: 2},
+			0x53: {fn: (*EVM).opMstore8, gasCost: gasVeryLow, dynamicGas: gasMstore8, minStack: 2},
+			0x54: {fn: (*EVM).opSload, minStack: 1},
+			0x55: {fn: (*EVM).opSstore, gasCost: gasZero, dynamicGas: gasSstore, minStack: 2, writes: true},
+			0x56: {fn: (*EVM).opJump, gasCost: gasMid, minStack: 1},
+			0x57: {fn: (*EVM).opJumpi, gasCost: gasHigh, minStack: 2},
+			0x58: {fn: (*EVM).opPc, gasCost: gasBase},
+			0x59: {fn: (*EVM).opMsize, gasCost: gasBase},
+			0x5a: {fn: (*EVM).opGas, gasCost: gasBase},
+			0x5b: {fn: (*EVM).opJumpdest, gasCost: gasJumpdest},
+			0x5c: {fn: (*EVM).opTload, gasCost: gasWarmAccess, minStack: 1, fork: Cancun},
+			0x5d: {fn: (*EVM).opTstore, gasCost: gasWarmAccess, minStack: 2, fork: Cancun, writes: true},
+			0x5e: {fn: (*EVM).opMcopy, gasCost: gasVeryLow, dynamicGas: gasMcopy, minStack: 3, fork: Cancun},
+			0x5f: {fn: (*EVM).opPush0, gasCost: gasBase, fork: Shanghai},
+			0xf0: {fn: (*EVM).opCreate, gasCost: gasCreate, dynamicGas: gasCreateCode, minStack: 3, writes: true},
+			0xf1: {fn: (*EVM).opCall, dynamicGas: gasCall, minStack: 7},
+			0xf2: {fn: (*EVM).opCallcode, dynamicGas: gasCallcode, minStack: 7},
+			0xf3: {fn: (*EVM).opReturn, gasCost: gasZero, dynamicGas: gasMemoryRange, minStack: 2},
+			0xf4: {fn: (*EVM).opDelegatecall, dynamicGas: gasCallNoValue, minStack: 6, fork: Homestead},
+			0xf5: {fn: (*EVM).opCreate2, gasCost: gasCreate, dynamicGas: gasCreate2, minStack: 4, fork: Constantinople, writes: true},
+			0xfa: {fn: (*EVM).opStaticcall, dynamicGas: gasCallNoValue, minStack: 6, fork: Byzantium},
+			0xfd: {fn: (*EVM).opRevert, gasCost: gasZero, dynamicGas: gasMemoryRange, minStack: 2, fork: Byzantium},
+			0xfe: {fn: (*EVM).opInvalid, gasCost: gasZero},
+			0xff: {fn: (*EVM).opSelfdestruct, dynamicGas: gasSelfdestruct, minStack: 1, writes: true},
 		},
 	}
+	for n := 1; n <= 32; n++ {
+		evm.opcodes[uint64(0x5f+n)] = opcode{fn: makePush(n), gasCost: gasVeryLow}
+	}
+	for n := 1; n <= 16; n++ {
+		evm.opcodes[uint64(0x7f+n)] = opcode{fn: makeDup(n), gasCost: gasVeryLow, minStack: n}
+		evm.opcodes[uint64(0x8f+n)] = opcode{fn: makeSwap(n), gasCost: gasVeryLow, minStack: n + 1}
+	}
+	for n := 0; n <= 4; n++ {
+		evm.opcodes[uint64(0xa0+n)] = opcode{fn: makeLog(n), gasCost: gasLog + uint64(n)*gasLogTopic, dynamicGas: gasLogData, minStack: n + 2, writes: true}
+	}
+	for _, opt := range opts {
+		opt(evm)
+	}
+	evm.schedule = gasScheduleFor(evm.config.Fork)
+	if evm.config.Gas != nil {
+		evm.schedule = *evm.config.Gas
+	}
+	evm.useGasSchedule()
+	evm.access = newAccessList()
+	if evm.config.Fork >= Berlin {
+		evm.useAccessLists()
+	}
 	return evm
 }
 
-func (evm *EVM) consumeGas(amount int) {
+// Reset prepares the EVM to run another program with the given gas: the
+// stack, memory, logs, refund and other per-run bookkeeping are cleared, while
+// the opcode table, context, config and tracer are kept. A State given with
+// WithState is kept too; the EVM's own default state is replaced by an empty
+// one. This lets instances be pooled, for example with sync.Pool.
+func (evm *EVM) Reset(gas uint64) {
+	evm.stack.data = evm.stack.data[:0]
+	evm.memory = evm.memory[:0]
+	if !evm.callerState {
+		evm.state = NewMemoryState(nil)
+	}
+	clear(evm.transient)
+	clear(evm.originals)
+	clear(evm.destructs)
+	evm.refund = 0
+	evm.journal = nil
+	evm.logs = nil
+	evm.gas = gas
+	evm.err = nil
+	evm.SetCode(nil)
+	evm.access = newAccessList()
+	if evm.config.Fork >= Berlin {
+		evm.warmAccessList()
+	}
+}
+
+// fail records err, located at the current instruction, as the reason
+// execution stopped and reports that it must halt. Like any exceptional halt
+// it consumes all remaining gas, at every depth; only ErrStepLimit, which
+// stops execution from outside, leaves the gas untouched.
+func (evm *EVM) fail(err error) bool {
+	if err != ErrStepLimit {
+		evm.gas = 0
+	}
+	err = &OpError{PC: evm.opPC, Op: evm.op, Err: err}
+	evm.err = err
+	if ft, ok := evm.tracer.(FaultTracer); ok {
+		ft.CaptureFault(err)
+	}
+	return true
+}
+
+// push appends a copy of v to the stack, faulting if that would exceed the
+// stack limit.
+func (evm *EVM) push(v *uint256.Int) bool {
+	if err := evm.stack.Push(v); err != nil {
+		return evm.fail(err)
+	}
+	return false
+}
+
+// pop and peek assume the stack is deep enough; dispatch checks each opcode's
+// minStack before running it.
+func (evm *EVM) pop() uint256.Int {
+	return evm.stack.pop()
+}
+
+// peek returns a pointer to the n-th item from the top of the stack, where 0
+// is the top. Writing through it updates the stack in place.
+func (evm *EVM) peek(n int) *uint256.Int {
+	return evm.stack.peek(n)
+}
+
+// consumeGas deducts amount from the remaining gas. Running out consumes all
+// remaining gas and returns ErrOutOfGas.
+func (evm *EVM) consumeGas(amount uint64) error {
 	if evm.gas < amount {
-		panic("Out of gas")
+		evm.gas = 0
+		return ErrOutOfGas
 	}
 	evm.gas -= amount
+	return nil
 }
 
 func (evm *EVM) opStop(bytecode []byte) bool {
 	return true
 }
 
+// The arithmetic, comparison and bitwise ops pop their first operand and write
+// the result over the second in place, so that they never allocate.
+
 func (evm *EVM) opAdd(bytecode []byte) bool {
-	n1 := evm.stack[len(evm.stack)-1]
-	n2 := evm.stack[len(evm.stack)-2]
-	evm.stack = evm.stack[:len(evm.stack)-2]
-	result := new(big.Int).Add(n1, n2)
-	result.Mod(result, bigPow(256))
-	evm.stack = append(evm.stack, result)
+	x := evm.pop()
+	y := evm.peek(0)
+	y.Add(&x, y)
 	return false
 }
 
 func (evm *EVM) opMul(bytecode []byte) bool {
-	n1 := evm.stack[len(evm.stack)-1]
-	n2 := evm.stack[len(evm.stack)-2]
-	evm.stack = evm.stack[:len(evm.stack)-2]
-	result := new(big.Int).Mul(n1, n2)
-	result.Mod(result, bigPow(256))
-	evm.stack = append(evm.stack, result)
+	x := evm.pop()
+	y := evm.peek(0)
+	y.Mul(&x, y)
 	return false
 }
 
 func (evm *EVM) opSub(bytecode []byte) bool {
-	n1 := evm.stack[len(evm.stack)-1]
-	n2 := evm.stack[len(evm.stack)-2]
-	evm.stack = evm.stack[:len(evm.stack)-2]
-	result := new(big.Int).Sub(n2, n1)
-	result.Mod(result, bigPow(256))
-	evm.stack = append(evm.stack, result)
+	x := evm.pop()
+	y := evm.peek(0)
+	y.Sub(&x, y)
 	return false
 }
 
 func (evm *EVM) opDiv(bytecode []byte) bool {
-	n1 := evm.stack[len(evm.stack)-1]
-	n2 := evm.stack[len(evm.stack)-2]
-	evm.stack = evm.stack[:len(evm.stack)-2]
-	result := new(big.Int)
-	if n1.Cmp(big.NewInt(0)) != 0 {
-		result.Div(n2, n1)
+	x := evm.pop()
+	y := evm.peek(0)
+	y.Div(&x, y)
+	return false
+}
+
+func (evm *EVM) opSdiv(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.peek(0)
+	y.SDiv(&x, y)
+	return false
+}
+
+func (evm *EVM) opMod(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.peek(0)
+	y.Mod(&x, y)
+	return false
+}
+
+func (evm *EVM) opSmod(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.peek(0)
+	y.SMod(&x, y)
+	return false
+}
+
+func (evm *EVM) opAddmod(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.pop()
+	m := evm.peek(0)
+	m.AddMod(&x, &y, m)
+	return false
+}
+
+func (evm *EVM) opMulmod(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.pop()
+	m := evm.peek(0)
+	m.MulMod(&x, &y, m)
+	return false
+}
+
+func (evm *EVM) opExp(bytecode []byte) bool {
+	base := evm.pop()
+	exponent := evm.peek(0)
+	exponent.Exp(&base, exponent)
+	return false
+}
+
+func (evm *EVM) opSignextend(bytecode []byte) bool {
+	b := evm.pop()
+	x := evm.peek(0)
+	x.ExtendSign(x, &b)
+	return false
+}
+
+func (evm *EVM) opLt(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.peek(0)
+	setBool(y, x.Lt(y))
+	return false
+}
+
+func (evm *EVM) opGt(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.peek(0)
+	setBool(y, x.Gt(y))
+	return false
+}
+
+func (evm *EVM) opSlt(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.peek(0)
+	setBool(y, x.Slt(y))
+	return false
+}
+
+func (evm *EVM) opSgt(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.peek(0)
+	setBool(y, x.Sgt(y))
+	return false
+}
+
+func (evm *EVM) opEq(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.peek(0)
+	setBool(y, x.Eq(y))
+	return false
+}
+
+func (evm *EVM) opIszero(bytecode []byte) bool {
+	x := evm.peek(0)
+	setBool(x, x.IsZero())
+	return false
+}
+
+func (evm *EVM) opAnd(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.peek(0)
+	y.And(&x, y)
+	return false
+}
+
+func (evm *EVM) opOr(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.peek(0)
+	y.Or(&x, y)
+	return false
+}
+
+func (evm *EVM) opXor(bytecode []byte) bool {
+	x := evm.pop()
+	y := evm.peek(0)
+	y.Xor(&x, y)
+	return false
+}
+
+func (evm *EVM) opNot(bytecode []byte) bool {
+	x := evm.peek(0)
+	x.Not(x)
+	return false
+}
+
+func (evm *EVM) opByte(bytecode []byte) bool {
+	i := evm.pop()
+	x := evm.peek(0)
+	x.Byte(&i)
+	return false
+}
+
+func (evm *EVM) opShl(bytecode []byte) bool {
+	shift := evm.pop()
+	value := evm.peek(0)
+	if shift.LtUint64(256) {
+		value.Lsh(value, uint(shift.Uint64()))
+	} else {
+		value.Clear()
 	}
-	result.Mod(result, bigPow(256))
-	evm.stack = append(evm.stack, result)
 	return false
 }
 
-func (evm *EVM) opPush1(bytecode []byte) bool {
-	if evm.pc >= len(bytecode) {
-		panic("Unexpected end of bytecode")
+func (evm *EVM) opShr(bytecode []byte) bool {
+	shift := evm.pop()
+	value := evm.peek(0)
+	if shift.LtUint64(256) {
+		value.Rsh(value, uint(shift.Uint64()))
+	} else {
+		value.Clear()
 	}
-	value := new(big.Int).SetUint64(uint64(bytecode[evm.pc]))
-	evm.stack = append(evm.stack, value)
-	evm.pc++
 	return false
 }
 
-func (evm *EVM) execute(bytecode []byte) {
-	stopExecution := false
-	for evm.pc < len(bytecode) && !stopExecution {
-		op := uint64(bytecode[evm.pc])
-		evm.pc++
+func (evm *EVM) opSar(bytecode []byte) bool {
+	shift := evm.pop()
+	value := evm.peek(0)
+	n := uint(255)
+	if shift.LtUint64(256) {
+		n = uint(shift.Uint64())
+	}
+	value.SRsh(value, n)
+	return false
+}
+
+func (evm *EVM) opSha3(bytecode []byte) bool {
+	offset := evm.pop()
+	length := evm.peek(0)
+	off, size, _ := toMemoryRange(&offset, length)
+	evm.expandMemory(off, size)
+	hash := Keccak256(evm.readMemory(off, size))
+	length.SetBytes32(hash[:])
+	return false
+}
+
+func (evm *EVM) opAddress(bytecode []byte) bool {
+	return evm.push(addressToWord(evm.ctx.Address))
+}
+
+func (evm *EVM) opBalance(bytecode []byte) bool {
+	addr := evm.peek(0)
+	addr.Set(evm.state.GetBalance(addr.Bytes20()))
+	return false
+}
+
+func (evm *EVM) opOrigin(bytecode []byte) bool {
+	return evm.push(addressToWord(evm.ctx.Origin))
+}
+
+func (evm *EVM) opCaller(bytecode []byte) bool {
+	return evm.push(addressToWord(evm.ctx.Caller))
+}
+
+func (evm *EVM) opCallvalue(bytecode []byte) bool {
+	return evm.push(wordFromBig(evm.ctx.CallValue))
+}
+
+func (evm *EVM) opCalldataload(bytecode []byte) bool {
+	offset := evm.peek(0)
+	offset.SetBytes(getData(evm.ctx.CallData, offset, 32))
+	return false
+}
+
+func (evm *EVM) opCalldatasize(bytecode []byte) bool {
+	return evm.push(uint256.NewInt(uint64(len(evm.ctx.CallData))))
+}
+
+func (evm *EVM) opCalldatacopy(bytecode []byte) bool {
+	destOffset := evm.pop()
+	offset := evm.pop()
+	length := evm.pop()
+	dest, size, _ := toMemoryRange(&destOffset, &length)
+	evm.writeMemory(dest, getData(evm.ctx.CallData, &offset, size))
+	return false
+}
+
+func (evm *EVM) opCodesize(bytecode []byte) bool {
+	return evm.push(uint256.NewInt(uint64(len(evm.code))))
+}
+
+func (evm *EVM) opCodecopy(bytecode []byte) bool {
+	destOffset := evm.pop()
+	offset := evm.pop()
+	length := evm.pop()
+	dest, size, _ := toMemoryRange(&destOffset, &length)
+	evm.writeMemory(dest, getData(evm.code, &offset, size))
+	return false
+}
+
+func (evm *EVM) opGasprice(bytecode []byte) bool {
+	return evm.push(wordFromBig(evm.ctx.GasPrice))
+}
+
+func (evm *EVM) opExtcodesize(bytecode []byte) bool {
+	addr := evm.peek(0)
+	addr.SetUint64(uint64(len(evm.state.GetCode(addr.Bytes20()))))
+	return false
+}
+
+func (evm *EVM) opExtcodecopy(bytecode []byte) bool {
+	addr := evm.pop()
+	destOffset := evm.pop()
+	offset := evm.pop()
+	length := evm.pop()
+	dest, size, _ := toMemoryRange(&destOffset, &length)
+	evm.writeMemory(dest, getData(evm.state.GetCode(addr.Bytes20()), &offset, size))
+	return false
+}
+
+// opExtcodehash pushes the keccak256 hash of an account's code, which for an
+// account without code is the hash of no bytes. It pushes 0 if the account
+// does not exist.
+func (evm *EVM) opExtcodehash(bytecode []byte) bool {
+	addr := evm.peek(0)
+	account := addr.Bytes20()
+	if !evm.state.Exist(account) {
+		addr.Clear()
+		return false
+	}
+	hash := Keccak256(evm.state.GetCode(account))
+	addr.SetBytes32(hash[:])
+	return false
+}
+
+func (evm *EVM) opReturndatasize(bytecode []byte) bool {
+	return evm.push(uint256.NewInt(uint64(len(evm.returnBuffer))))
+}
+
+// opReturndatacopy faults rather than zero-filling if the range runs past the
+// end of the return data.
+func (evm *EVM) opReturndatacopy(bytecode []byte) bool {
+	destOffset := evm.pop()
+	offset := evm.pop()
+	length := evm.pop()
+	end, overflow := new(uint256.Int).AddOverflow(&offset, &length)
+	if overflow || !end.IsUint64() || end.Uint64() > uint64(len(evm.returnBuffer)) {
+		return evm.fail(ErrReturnDataOutOfBounds)
+	}
+	dest, _, _ := toMemoryRange(&destOffset, &length)
+	evm.writeMemory(dest, evm.returnBuffer[offset.Uint64():end.Uint64()])
+	return false
+}
+
+// opBlockhash pushes the hash of one of the 256 most recent blocks before the
+// current one, or 0 for any other block number.
+func (evm *EVM) opBlockhash(bytecode []byte) bool {
+	num := evm.peek(0)
+	current := wordFromBig(evm.ctx.BlockNumber)
+	lowest := new(uint256.Int)
+	if current.GtUint64(256) {
+		lowest.SubUint64(current, 256)
+	}
+	if evm.ctx.GetBlockHash == nil || !num.Lt(current) || num.Lt(lowest) {
+		num.Clear()
+		return false
+	}
+	hash := evm.ctx.GetBlockHash(num.Uint64())
+	num.SetBytes(hash[:])
+	return false
+}
+
+func (evm *EVM) opCoinbase(bytecode []byte) bool {
+	return evm.push(addressToWord(evm.ctx.Coinbase))
+}
+
+func (evm *EVM) opTimestamp(bytecode []byte) bool {
+	return evm.push(wordFromBig(evm.ctx.Timestamp))
+}
+
+func (evm *EVM) opNumber(bytecode []byte) bool {
+	return evm.push(wordFromBig(evm.ctx.BlockNumber))
+}
+
+func (evm *EVM) opDifficulty(bytecode []byte) bool {
+	return evm.push(new(uint256.Int).SetBytes(evm.ctx.PrevRandao[:]))
+}
+
+func (evm *EVM) opGaslimit(bytecode []byte) bool {
+	return evm.push(uint256.NewInt(evm.ctx.GasLimit))
+}
+
+func (evm *EVM) opChainid(bytecode []byte) bool {
+	if evm.ctx.ChainID == nil {
+		return evm.push(uint256.NewInt(1))
+	}
+	return evm.push(wordFromBig(evm.ctx.ChainID))
+}
+
+func (evm *EVM) opSelfbalance(bytecode []byte) bool {
+	return evm.push(evm.state.GetBalance(evm.ctx.Address))
+}
+
+func (evm *EVM) opBasefee(bytecode []byte) bool {
+	return evm.push(wordFromBig(evm.ctx.BaseFee))
+}
+
+// opBlobhash pushes the versioned hash of the transaction's blob at the given
+// index, or 0 if there is no such blob.
+func (evm *EVM) opBlobhash(bytecode []byte) bool {
+	index := evm.peek(0)
+	if !index.LtUint64(uint64(len(evm.ctx.BlobHashes))) {
+		index.Clear()
+		return false
+	}
+	hash := evm.ctx.BlobHashes[index.Uint64()]
+	index.SetBytes(hash[:])
+	return false
+}
+
+func (evm *EVM) opBlobbasefee(bytecode []byte) bool {
+	return evm.push(wordFromBig(evm.ctx.BlobBaseFee))
+}
+
+func (evm *EVM) opPop(bytecode []byte) bool {
+	evm.pop()
+	return false
+}
+
+func (evm *EVM) opMload(bytecode []byte) bool {
+	offset := evm.peek(0)
+	off, _, _ := toMemoryRange(offset, uint256.NewInt(32))
+	evm.expandMemory(off, 32)
+	offset.SetBytes(evm.readMemory(off, 32))
+	return false
+}
+
+func (evm *EVM) opMstore(bytecode []byte) bool {
+	offset := evm.pop()
+	value := evm.pop()
+	off, _, _ := toMemoryRange(&offset, uint256.NewInt(32))
+	word := value.Bytes32()
+	evm.writeMemory(off, word[:])
+	return false
+}
+
+func (evm *EVM) opMstore8(bytecode []byte) bool {
+	offset := evm.pop()
+	value := evm.pop()
+	off, _, _ := toMemoryRange(&offset, uint256.NewInt(1))
+	evm.writeMemory(off, []byte{byte(value.Uint64())})
+	return false
+}
+
+func (evm *EVM) opSload(bytecode []byte) bool {
+	key := evm.peek(0)
+	key.Set(evm.state.GetStorage(evm.ctx.Address, Hash(key.Bytes32())))
+	return false
+}
 
-		if opcode, ok := evm.opcodes[op]; ok {
-			evm.consumeGas(opcode.gasCost)
-			stopExecution = opcode.fn(evm, bytecode)
+func (evm *EVM) opSstore(bytecode []byte) bool {
+	key := evm.pop()
+	value := evm.pop()
+	evm.setStorage(Hash(key.Bytes32()), &value)
+	return false
+}
+
+func (evm *EVM) opTload(bytecode []byte) bool {
+	key := evm.peek(0)
+	if value, ok := evm.transient[slotKey{addr: evm.ctx.Address, key: Hash(key.Bytes32())}]; ok {
+		key.Set(value)
+	} else {
+		key.Clear()
+	}
+	return false
+}
+
+func (evm *EVM) opTstore(bytecode []byte) bool {
+	key := evm.pop()
+	value := evm.pop()
+	evm.setTransient(Hash(key.Bytes32()), &value)
+	return false
+}
+
+func (evm *EVM) opMcopy(bytecode []byte) bool {
+	destOffset := evm.pop()
+	offset := evm.pop()
+	length := evm.pop()
+	dest, size, _ := toMemoryRange(&destOffset, &length)
+	src, _, _ := toMemoryRange(&offset, &length)
+	evm.expandMemory(src, size)
+	// readMemory copies, so overlapping ranges behave like memmove.
+	evm.writeMemory(dest, evm.readMemory(src, size))
+	return false
+}
+
+func (evm *EVM) opJump(bytecode []byte) bool {
+	dest := evm.pop()
+	return evm.jumpTo(&dest)
+}
+
+func (evm *EVM) opJumpi(bytecode []byte) bool {
+	dest := evm.pop()
+	cond := evm.pop()
+	if cond.IsZero() {
+		return false
+	}
+	return evm.jumpTo(&dest)
+}
+
+func (evm *EVM) opPc(bytecode []byte) bool {
+	// dispatch has already advanced pc past this opcode.
+	return evm.push(uint256.NewInt(uint64(evm.pc - 1)))
+}
+
+func (evm *EVM) opMsize(bytecode []byte) bool {
+	return evm.push(uint256.NewInt(uint64(len(evm.memory))))
+}
+
+func (evm *EVM) opGas(bytecode []byte) bool {
+	return evm.push(uint256.NewInt(evm.gas))
+}
+
+func (evm *EVM) opJumpdest(bytecode []byte) bool {
+	return false
+}
+
+func (evm *EVM) jumpTo(dest *uint256.Int) bool {
+	if dest.BitLen() > 32 || !evm.jumpdests[int(dest.Uint64())] {
+		return evm.fail(ErrInvalidJump)
+	}
+	evm.pc = int(dest.Uint64())
+	return false
+}
+
+func (evm *EVM) opReturn(bytecode []byte) bool {
+	offset := evm.pop()
+	length := evm.pop()
+	off, size, _ := toMemoryRange(&offset, &length)
+	evm.expandMemory(off, size)
+	evm.returnData = evm.readMemory(off, size)
+	return true
+}
+
+func (evm *EVM) opRevert(bytecode []byte) bool {
+	evm.opReturn(bytecode)
+	evm.reverted = true
+	return true
+}
+
+// opInvalid is the designated invalid instruction, which always faults.
+func (evm *EVM) opInvalid(bytecode []byte) bool {
+	return evm.fail(ErrInvalidOpcode)
+}
+
+// opSelfdestruct sends the contract's whole balance to a beneficiary, marks
+// the contract for deletion at the end of the transaction, and halts. Before
+// London the first self-destruct of a contract also earns a refund.
+func (evm *EVM) opSelfdestruct(bytecode []byte) bool {
+	beneficiary := evm.pop()
+	self := evm.ctx.Address
+	if evm.config.Fork < London && !evm.destructs[self] {
+		evm.addRefund(gasSelfdestructRefund)
+	}
+	evm.transfer(self, beneficiary.Bytes20(), evm.state.GetBalance(self))
+	evm.destruct(self)
+	return true
+}
+
+func (evm *EVM) opPush0(bytecode []byte) bool {
+	return evm.push(new(uint256.Int))
+}
+
+// makePush returns the handler for PUSHn, which pushes the n bytes of code
+// that follow it and steps over them. Bytes past the end of the code read as
+// zero.
+func makePush(n int) func(*EVM, []byte) bool {
+	return func(evm *EVM, bytecode []byte) bool {
+		var data [32]byte
+		copy(data[:n], bytecode[evm.pc:min(evm.pc+n, len(bytecode))])
+		evm.pc += n
+		var value uint256.Int
+		value.SetBytes(data[:n])
+		return evm.push(&value)
+	}
+}
+
+// makeDup returns the handler for DUPn, which pushes a copy of the n-th stack item.
+func makeDup(n int) func(*EVM, []byte) bool {
+	return func(evm *EVM, bytecode []byte) bool {
+		if err := evm.stack.Dup(n); err != nil {
+			return evm.fail(err)
+		}
+		return false
+	}
+}
+
+// makeSwap returns the handler for SWAPn, which exchanges the top stack item
+// with the (n+1)-th.
+func makeSwap(n int) func(*EVM, []byte) bool {
+	return func(evm *EVM, bytecode []byte) bool {
+		if err := evm.stack.Swap(n); err != nil {
+			return evm.fail(err)
+		}
+		return false
+	}
+}
+
+// SetCode loads bytecode for execution and rewinds the program counter so
+// that it can be run with Step or Execute.
+func (evm *EVM) SetCode(bytecode []byte) {
+	evm.code = bytecode
+	evm.jumpdests = jumpdestAnalysis(bytecode)
+	evm.pc = 0
+	evm.halted = false
+	evm.steps = 0
+	evm.returnData = nil
+	evm.returnBuffer = nil
+	evm.reverted = false
+}
+
+// Step executes exactly one instruction at the current pc. It reports done
+// once execution has halted, whether by STOP, a fault, or running off the end
+// of the code, and returns the fault if there was one.
+func (evm *EVM) Step() (done bool, err error) {
+	if !evm.halted && evm.pc < len(evm.code) {
+		if evm.MaxSteps > 0 && evm.stepBase+evm.steps >= evm.MaxSteps {
+			// Reported at the instruction that was not run.
+			evm.opPC, evm.op = evm.pc, uint64(evm.code[evm.pc])
+			evm.halted = evm.fail(ErrStepLimit)
 		} else {
-			if 0x60 <= op && op <= 0x7f {
-				numBytes := int(op - 0x5f)
-				value := big.NewInt(0)
-				for i := 0; i < numBytes; i++ {
-					value = value.Lsh(value, 8)
-					value = value.Add(value, big.NewInt(int64(bytecode[evm.pc+i])))
-				}
-				evm.stack = append(evm.stack, value)
-				evm.pc += numBytes
+			evm.steps++
+			// A child frame that reaches the step limit leaves its error
+			// here, which halts this frame too.
+			evm.halted = evm.dispatch(evm.code) || evm.err != nil
+		}
+	}
+	if evm.pc >= len(evm.code) {
+		evm.halted = true
+	}
+	return evm.halted, evm.err
+}
+
+// dispatch runs the opcode at pc and reports whether execution must halt.
+func (evm *EVM) dispatch(bytecode []byte) bool {
+	op := uint64(bytecode[evm.pc])
+	evm.opPC, evm.op = evm.pc, op
+	opcode, ok := evm.opcodes[op]
+	ok = ok && opcode.fork <= evm.config.Fork
+
+	// The cost is worked out before the tracer is called so that it can be
+	// reported alongside the instruction.
+	var err error
+	var cost uint64
+	switch {
+	case ok && evm.stack.Len() < opcode.minStack:
+		err = ErrStackUnderflow
+	case ok && opcode.writes && evm.readOnly:
+		err = ErrWriteProtection
+	case ok:
+		cost = opcode.gasCost
+		if opcode.dynamicGas != nil {
+			if dynamic := opcode.dynamicGas(evm); dynamic > math.MaxUint64-cost {
+				cost = math.MaxUint64
 			} else {
-				panic(fmt.Sprintf("Invalid opcode: %x", op))
+				cost += dynamic
 			}
 		}
 	}
+	evm.trace(op, cost)
+	evm.pc++
+
+	if !ok {
+		return evm.fail(ErrInvalidOpcode)
+	}
+	if err != nil {
+		return evm.fail(err)
+	}
+	if err := evm.consumeGas(cost); err != nil {
+		return evm.fail(err)
+	}
+	return opcode.fn(evm, bytecode)
+}
+
+// trace reports the instruction about to run to the tracer, if there is one.
+func (evm *EVM) trace(op uint64, cost uint64) {
+	switch t := evm.tracer.(type) {
+	case nil:
+	case StepTracer:
+		t.CaptureStep(evm.pc, op, evm.gas, cost, evm.refund, evm.depth+1, evm.Stack(), evm.memory)
+	default:
+		t.CaptureState(evm.pc, op, evm.gas, evm.Stack(), evm.memory)
+	}
+}
+
+// makeLog returns the handler for LOGn, which records a log with n topics.
+func makeLog(n int) func(*EVM, []byte) bool {
+	return func(evm *EVM, bytecode []byte) bool {
+		offset := evm.pop()
+		length := evm.pop()
+		topics := make([][32]byte, n)
+		for i := range topics {
+			topic := evm.pop()
+			topics[i] = topic.Bytes32()
+		}
+		off, size, _ := toMemoryRange(&offset, &length)
+		evm.expandMemory(off, size)
+		evm.addLog(Log{
+			Address: evm.ctx.Address,
+			Topics:  topics,
+			Data:    evm.readMemory(off, size),
+		})
+		return false
+	}
 }
 
-func bigPow(exp int) *big.Int {
-	pow := big.NewInt(1)
-	return pow.Lsh(pow, uint(exp))
+// Execute runs bytecode from the start until it halts and returns the outcome.
+// State changes and logs are rolled back if execution reverts or faults.
+func (evm *EVM) Execute(bytecode []byte) ExecutionResult {
+	startGas := evm.gas
+	startLogs := len(evm.logs)
+	snapshot := evm.snapshot()
+	evm.SetCode(bytecode)
+	for {
+		if done, _ := evm.Step(); done {
+			break
+		}
+	}
+	if evm.reverted || evm.err != nil {
+		evm.revertTo(snapshot)
+	}
+	gasUsed := startGas - evm.gas
+	maxRefund := gasUsed / 2
+	if evm.config.Fork >= London {
+		maxRefund = gasUsed / 5
+	}
+	var warnings []string
+	if evm.strictStack && !evm.reverted && evm.err == nil && evm.stack.Len() > 0 {
+		warnings = append(warnings, fmt.Sprintf("stack depth %d at halt", evm.stack.Len()))
+	}
+	return ExecutionResult{
+		Success:    !evm.reverted && evm.err == nil,
+		ReturnData: evm.returnData,
+		Logs:       append([]Log(nil), evm.logs[startLogs:]...),
+		GasUsed:    gasUsed,
+		GasLeft:    evm.gas,
+		GasRefund:  min(evm.refund, maxRefund),
+		Reverted:   evm.reverted,
+		Err:        evm.err,
+		Warnings:   warnings,
+	}
 }
 
-func main() {
-	initialGas := 1000
-	evm := NewEVM(initialGas)
-	bytecode := []byte{0x60, 0x05, 0x60, 0x05, 0x02, 0x00}
-	evm.execute(bytecode)
-	fmt.Println(evm.stack)
-	fmt.Printf("Remaining gas: %d\n", evm.gas)
+// setBool sets x to 1 if b is true and to 0 otherwise.
+func setBool(x *uint256.Int, b bool) {
+	if b {
+		x.SetOne()
+	} else {
+		x.Clear()
+	}
 }
@@ -0,0 +1,66 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// pushes returns code pushing 1, 2, ..., n, leaving n on top.
+func pushes(n int) []byte {
+	var code []byte
+	for i := 1; i <= n; i++ {
+		code = append(code, 0x60, byte(i))
+	}
+	return code
+}
+
+// TestDupSwapMatrix runs every DUPn and SWAPn on a stack just deep enough and
+// on one that is one item short.
+func TestDupSwapMatrix(t *testing.T) {
+	for n := 1; n <= 16; n++ {
+		dup, swap := byte(0x7f+n), byte(0x8f+n)
+
+		t.Run(fmt.Sprintf("DUP%d", n), func(t *testing.T) {
+			evm := NewEVM(WithGas(1000))
+			if result := evm.Execute(append(pushes(n), dup)); result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			// The n-th item from the top is the first one pushed.
+			stack := evm.Stack()
+			if len(stack) != n+1 || stack[n].Int64() != 1 {
+				t.Fatalf("stack = %v", stack)
+			}
+			assertUnderflow(t, append(pushes(n-1), dup), dup)
+		})
+
+		t.Run(fmt.Sprintf("SWAP%d", n), func(t *testing.T) {
+			evm := NewEVM(WithGas(1000))
+			if result := evm.Execute(append(pushes(n+1), swap)); result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			stack := evm.Stack()
+			if len(stack) != n+1 || stack[n].Int64() != 1 || stack[0].Int64() != int64(n+1) {
+				t.Fatalf("stack = %v", stack)
+			}
+			for i := 1; i < n; i++ {
+				if stack[i].Int64() != int64(i+1) {
+					t.Fatalf("stack = %v, item %d moved", stack, i)
+				}
+			}
+			assertUnderflow(t, append(pushes(n), swap), swap)
+		})
+	}
+}
+
+func assertUnderflow(t *testing.T, code []byte, op byte) {
+	t.Helper()
+	result := NewEVM(WithGas(1000)).Execute(code)
+	var opErr *OpError
+	if !errors.As(result.Err, &opErr) || !errors.Is(result.Err, ErrStackUnderflow) {
+		t.Fatalf("err = %v, want a stack underflow", result.Err)
+	}
+	if opErr.Op != uint64(op) || opErr.PC != len(code)-1 {
+		t.Fatalf("fault at %s (pc %d), want %s (pc %d)", OpName(opErr.Op), opErr.PC, OpName(uint64(op)), len(code)-1)
+	}
+}
@@ -0,0 +1,67 @@
+package evm
+
+// DefaultGas is the gas an EVM starts with when WithGas is not given.
+const DefaultGas = 10000000
+
+// Option configures an EVM created by NewEVM.
+type Option func(*EVM)
+
+// WithGas sets the gas available to execution.
+func WithGas(gas uint64) Option {
+	return func(evm *EVM) {
+		evm.gas = gas
+	}
+}
+
+// WithContext sets the block, transaction and message context. It replaces the
+// whole context, so pass it before WithCallData if using both.
+func WithContext(ctx Context) Option {
+	return func(evm *EVM) {
+		evm.ctx = ctx
+	}
+}
+
+// WithTracer installs a tracer that is called before every instruction.
+func WithTracer(tracer Tracer) Option {
+	return func(evm *EVM) {
+		evm.tracer = tracer
+	}
+}
+
+// WithCallData sets the input data read by the CALLDATA* opcodes.
+func WithCallData(data []byte) Option {
+	return func(evm *EVM) {
+		evm.ctx.CallData = data
+	}
+}
+
+// WithMaxSteps limits execution to n instructions. Zero means no limit.
+func WithMaxSteps(n int) Option {
+	return func(evm *EVM) {
+		evm.MaxSteps = n
+	}
+}
+
+// WithStrictStack reports, in ExecutionResult.Warnings, a program that halts
+// successfully with items left on the stack. This is legal but often a bug in
+// hand-written code.
+func WithStrictStack() Option {
+	return func(evm *EVM) {
+		evm.strictStack = true
+	}
+}
+
+// WithConfig selects the protocol upgrades to follow instead of DefaultConfig.
+func WithConfig(cfg Config) Option {
+	return func(evm *EVM) {
+		evm.config = cfg
+	}
+}
+
+// WithState runs against the given world state instead of an empty MemoryState.
+func WithState(state State) Option {
+	return func(evm *EVM) {
+		evm.state = state
+		evm.callerState = true
+	}
+}
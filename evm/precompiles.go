@@ -0,0 +1,232 @@
+package evm
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// precompile is a contract implemented natively rather than in bytecode. gas
+// returns the cost of running it on input.
+type precompile struct {
+	gas  func(input []byte) uint64
+	run  func(input []byte) ([]byte, error)
+	fork Fork
+}
+
+var precompiles = map[[20]byte]precompile{
+	{19: 0x01}: {gas: fixedGas(3000), run: runEcrecover},
+	{19: 0x02}: {gas: wordGas(60, 12), run: runSha256},
+	{19: 0x03}: {gas: wordGas(600, 120), run: runRipemd160},
+	{19: 0x04}: {gas: wordGas(15, 3), run: runIdentity},
+	{19: 0x05}: {gas: modexpGas, run: runModexp, fork: Byzantium},
+}
+
+// precompile returns the precompiled contract at addr, if the active fork has one.
+func (evm *EVM) precompile(addr [20]byte) (precompile, bool) {
+	p, ok := precompiles[addr]
+	return p, ok && p.fork <= evm.config.Fork
+}
+
+// runPrecompile charges for and runs p, returning its output, the gas left
+// over and whether it succeeded. Failure consumes all the gas.
+func runPrecompile(p precompile, input []byte, gas uint64) ([]byte, uint64, bool) {
+	cost := p.gas(input)
+	// MaxUint64 marks a cost too large to price, which no gas can cover.
+	if gas < cost || cost == math.MaxUint64 {
+		return nil, 0, false
+	}
+	output, err := p.run(input)
+	if err != nil {
+		return nil, 0, false
+	}
+	return output, gas - cost, true
+}
+
+func fixedGas(gas uint64) func([]byte) uint64 {
+	return func([]byte) uint64 {
+		return gas
+	}
+}
+
+// wordGas returns a gas function charging base plus perWord for every 32-byte
+// word of input.
+func wordGas(base, perWord uint64) func([]byte) uint64 {
+	return func(input []byte) uint64 {
+		return base + perWord*uint64((len(input)+31)/32)
+	}
+}
+
+// runEcrecover recovers the address that signed a hash. The input is hash, v,
+// r and s as 32-byte words; an invalid signature yields empty output rather
+// than an error. Unlike Ecrecover, high-s signatures are accepted.
+func runEcrecover(input []byte) ([]byte, error) {
+	input = rightPad(input, 128)
+	v := new(big.Int).SetBytes(input[32:64])
+	r := new(big.Int).SetBytes(input[64:96])
+	s := new(big.Int).SetBytes(input[96:128])
+	addr, err := ecrecover([32]byte(input[:32]), v, r, s)
+	if err != nil {
+		return nil, nil
+	}
+	out := make([]byte, 32)
+	copy(out[12:], addr[:])
+	return out, nil
+}
+
+// ErrInvalidSignature is returned by Ecrecover for a signature that does not
+// recover to a public key.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// secp256k1HalfN is half the order of the secp256k1 group, the largest s
+// that EIP-2 allows.
+var secp256k1HalfN = new(big.Int).Rsh(secp256k1.Params().N, 1)
+
+// Ecrecover returns the address whose key produced the signature (v, r, s)
+// over hash. v must be 27 or 28 and, as EIP-2 requires of transaction
+// signatures, s must not exceed half the curve order.
+func Ecrecover(hash [32]byte, v, r, s *big.Int) (Address, error) {
+	if s.Cmp(secp256k1HalfN) > 0 {
+		return Address{}, ErrInvalidSignature
+	}
+	return ecrecover(hash, v, r, s)
+}
+
+// ecrecover recovers the signer of hash without the EIP-2 bound on s.
+func ecrecover(hash [32]byte, v, r, s *big.Int) (Address, error) {
+	if !v.IsInt64() || (v.Int64() != 27 && v.Int64() != 28) {
+		return Address{}, ErrInvalidSignature
+	}
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.BitLen() > 256 || s.BitLen() > 256 {
+		return Address{}, ErrInvalidSignature
+	}
+	sig := make([]byte, 65)
+	sig[0] = byte(v.Int64())
+	r.FillBytes(sig[1:33])
+	s.FillBytes(sig[33:])
+	pub, _, err := ecdsa.RecoverCompact(sig, hash[:])
+	if err != nil {
+		return Address{}, ErrInvalidSignature
+	}
+	digest := Keccak256(pub.SerializeUncompressed()[1:])
+	return BytesToAddress(digest[12:]), nil
+}
+
+func runSha256(input []byte) ([]byte, error) {
+	sum := sha256.Sum256(input)
+	return sum[:], nil
+}
+
+func runRipemd160(input []byte) ([]byte, error) {
+	hasher := ripemd160.New()
+	hasher.Write(input)
+	out := make([]byte, 32)
+	copy(out[12:], hasher.Sum(nil))
+	return out, nil
+}
+
+func runIdentity(input []byte) ([]byte, error) {
+	return append([]byte(nil), input...), nil
+}
+
+// modexpLengths parses the base, exponent and modulus lengths that prefix a
+// modexp input.
+func modexpLengths(input []byte) (baseLen, expLen, modLen *big.Int) {
+	return new(big.Int).SetBytes(getBytes(input, 0, 32)),
+		new(big.Int).SetBytes(getBytes(input, 32, 32)),
+		new(big.Int).SetBytes(getBytes(input, 64, 32))
+}
+
+// modexpGas prices modexp per EIP-2565: the square of the operand size in
+// 8-byte words, times the number of squarings the exponent needs, over 3,
+// with a floor of 200.
+func modexpGas(input []byte) uint64 {
+	baseLen, expLen, modLen := modexpLengths(input)
+	if !baseLen.IsUint64() || !expLen.IsUint64() || !modLen.IsUint64() {
+		return math.MaxUint64
+	}
+
+	// The word count is worked out in big.Int, as the lengths may be near
+	// 2^64 and would wrap.
+	words := new(big.Int).Set(baseLen)
+	if modLen.Cmp(baseLen) > 0 {
+		words.Set(modLen)
+	}
+	words.Add(words, big.NewInt(7))
+	words.Rsh(words, 3)
+	complexity := words.Mul(words, words)
+
+	// The iteration count depends on the bit length of the exponent's first
+	// 32 bytes, plus 8 per byte beyond them.
+	head := new(big.Int).SetBytes(getBytes(input, addOffsets(96, baseLen.Uint64()), min(expLen.Uint64(), 32)))
+	iterations := new(big.Int)
+	if expLen.Uint64() > 32 {
+		iterations.SetUint64(expLen.Uint64() - 32)
+		iterations.Lsh(iterations, 3)
+	}
+	if bits := head.BitLen(); bits > 1 {
+		iterations.Add(iterations, big.NewInt(int64(bits-1)))
+	}
+	if iterations.Sign() == 0 {
+		iterations.SetUint64(1)
+	}
+
+	gas := complexity.Mul(complexity, iterations)
+	gas.Div(gas, big.NewInt(3))
+	if !gas.IsUint64() {
+		return math.MaxUint64
+	}
+	return max(gas.Uint64(), 200)
+}
+
+// runModexp computes base**exp % mod, returned left-padded to the modulus
+// length. A zero modulus yields zero.
+func runModexp(input []byte) ([]byte, error) {
+	baseLen, expLen, modLen := modexpLengths(input)
+	bl, el, ml := baseLen.Uint64(), expLen.Uint64(), modLen.Uint64()
+	if ml == 0 {
+		return []byte{}, nil
+	}
+	base := new(big.Int).SetBytes(getBytes(input, 96, bl))
+	exp := new(big.Int).SetBytes(getBytes(input, addOffsets(96, bl), el))
+	mod := new(big.Int).SetBytes(getBytes(input, addOffsets(addOffsets(96, bl), el), ml))
+	out := make([]byte, ml)
+	if mod.Sign() == 0 {
+		return out, nil
+	}
+	return new(big.Int).Exp(base, exp, mod).FillBytes(out), nil
+}
+
+// getBytes returns size bytes of data starting at start, zero-padded where
+// the range runs past the end of data.
+func getBytes(data []byte, start, size uint64) []byte {
+	out := make([]byte, size)
+	if start < uint64(len(data)) {
+		copy(out, data[start:])
+	}
+	return out
+}
+
+// addOffsets adds two input offsets, saturating at math.MaxUint64 so that an
+// offset past the end of any input never wraps back into it.
+func addOffsets(a, b uint64) uint64 {
+	if b > math.MaxUint64-a {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+// rightPad returns data zero-extended to at least size bytes.
+func rightPad(data []byte, size int) []byte {
+	if len(data) >= size {
+		return data
+	}
+	out := make([]byte, size)
+	copy(out, data)
+	return out
+}
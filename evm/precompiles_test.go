@@ -0,0 +1,47 @@
+package evm
+
+import (
+	"math"
+	"testing"
+)
+
+// TestModexpHugeLengths checks that modexp lengths near 2^64 are priced out
+// of reach rather than wrapping to the minimum cost and allocating them.
+func TestModexpHugeLengths(t *testing.T) {
+	for _, field := range []int{0, 2} {
+		input := make([]byte, 96)
+		for i := 0; i < 8; i++ {
+			input[32*field+24+i] = 0xff
+		}
+		input[95] = 1 // a non-zero modulus length
+		if gas := modexpGas(input); gas != math.MaxUint64 {
+			t.Errorf("length field %d: gas = %d, want MaxUint64", field, gas)
+		}
+		if _, _, ok := runPrecompile(precompiles[[20]byte{19: 0x05}], input, math.MaxUint64); ok {
+			t.Errorf("length field %d: precompile succeeded", field)
+		}
+	}
+}
+
+// TestModexpCallHugeLength runs the STATICCALL that used to crash: a base
+// length of 2^64-1 with nothing else set.
+func TestModexpCallHugeLength(t *testing.T) {
+	code, err := Assemble(`
+	PUSH8 0xffffffffffffffff
+	PUSH1 0
+	MSTORE
+	PUSH1 0   ; retSize
+	PUSH1 0   ; retOffset
+	PUSH1 96  ; argsSize
+	PUSH1 0   ; argsOffset
+	PUSH1 5   ; modexp
+	GAS
+	STATICCALL`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := NewEVM(WithGas(1000000)).Execute(code)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+}
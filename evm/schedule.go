@@ -0,0 +1,169 @@
+package evm
+
+// GasSchedule holds the gas costs that have changed between forks. The fork
+// still decides which pricing rules apply, such as EIP-2200 SSTORE metering or
+// EIP-2929 access lists; the schedule supplies the numbers they use.
+type GasSchedule struct {
+	// Static costs of SLOAD, BALANCE, EXTCODESIZE and EXTCODECOPY,
+	// EXTCODEHASH, the CALL family and SELFDESTRUCT. Under access lists these
+	// are replaced by WarmAccess.
+	Sload        uint64
+	Balance      uint64
+	Extcode      uint64
+	ExtcodeHash  uint64
+	Call         uint64
+	Selfdestruct uint64
+
+	// SHA3 costs Sha3 plus Sha3Word per word hashed; CREATE2 also pays
+	// Sha3Word per word of init code. EXP costs ExpByte per exponent byte.
+	Sha3     uint64
+	Sha3Word uint64
+	ExpByte  uint64
+
+	// Memory of n words costs Memory*n + n*n/MemoryQuadDivisor in total.
+	Memory            uint64
+	MemoryQuadDivisor uint64
+
+	// SstoreSet is charged for writing a zero slot and SstoreReset for any
+	// other change; clearing a slot earns SstoreClearRefund.
+	SstoreSet         uint64
+	SstoreReset       uint64
+	SstoreClearRefund uint64
+
+	// CallValue is charged for a call that transfers value, and NewAccount
+	// when a CALL or SELFDESTRUCT brings a new account into existence.
+	CallValue  uint64
+	NewAccount uint64
+
+	// EIP-2929 access list prices, used from Berlin.
+	WarmAccess  uint64
+	ColdSload   uint64
+	ColdAccount uint64
+}
+
+// FrontierGas is the original schedule, used for Frontier and Homestead.
+var FrontierGas = GasSchedule{
+	Sload:             50,
+	Balance:           20,
+	Extcode:           20,
+	ExtcodeHash:       20,
+	Call:              40,
+	Selfdestruct:      0,
+	Sha3:              30,
+	Sha3Word:          6,
+	ExpByte:           10,
+	Memory:            3,
+	MemoryQuadDivisor: 512,
+	SstoreSet:         20000,
+	SstoreReset:       5000,
+	SstoreClearRefund: 15000,
+	CallValue:         9000,
+	NewAccount:        25000,
+}
+
+// ByzantiumGas is the schedule used from Byzantium through Constantinople,
+// with the repricings of EIP-150 and EIP-160.
+var ByzantiumGas = GasSchedule{
+	Sload:             200,
+	Balance:           400,
+	Extcode:           700,
+	ExtcodeHash:       400,
+	Call:              700,
+	Selfdestruct:      5000,
+	Sha3:              30,
+	Sha3Word:          6,
+	ExpByte:           50,
+	Memory:            3,
+	MemoryQuadDivisor: 512,
+	SstoreSet:         20000,
+	SstoreReset:       5000,
+	SstoreClearRefund: 15000,
+	CallValue:         9000,
+	NewAccount:        25000,
+}
+
+// IstanbulGas is the schedule used for Istanbul, which adds the EIP-1884
+// repricing of SLOAD, BALANCE and EXTCODEHASH.
+var IstanbulGas = GasSchedule{
+	Sload:             800,
+	Balance:           700,
+	Extcode:           700,
+	ExtcodeHash:       700,
+	Call:              700,
+	Selfdestruct:      5000,
+	Sha3:              30,
+	Sha3Word:          6,
+	ExpByte:           50,
+	Memory:            3,
+	MemoryQuadDivisor: 512,
+	SstoreSet:         20000,
+	SstoreReset:       5000,
+	SstoreClearRefund: 15000,
+	CallValue:         9000,
+	NewAccount:        25000,
+}
+
+// LondonGas is the schedule used from London, with the access list prices of
+// EIP-2929 and the smaller clearing refund of EIP-3529.
+var LondonGas = GasSchedule{
+	Sload:             100,
+	Balance:           100,
+	Extcode:           100,
+	ExtcodeHash:       100,
+	Call:              100,
+	Selfdestruct:      5000,
+	Sha3:              30,
+	Sha3Word:          6,
+	ExpByte:           50,
+	Memory:            3,
+	MemoryQuadDivisor: 512,
+	SstoreSet:         20000,
+	SstoreReset:       5000,
+	SstoreClearRefund: 4800,
+	CallValue:         9000,
+	NewAccount:        25000,
+	WarmAccess:        100,
+	ColdSload:         2100,
+	ColdAccount:       2600,
+}
+
+// gasScheduleFor returns the schedule a fork uses by default.
+func gasScheduleFor(fork Fork) GasSchedule {
+	switch {
+	case fork >= London:
+		return LondonGas
+	case fork >= Berlin:
+		// Berlin has London's access list prices but the old refund.
+		s := LondonGas
+		s.SstoreClearRefund = IstanbulGas.SstoreClearRefund
+		return s
+	case fork >= Istanbul:
+		return IstanbulGas
+	case fork >= Byzantium:
+		return ByzantiumGas
+	default:
+		return FrontierGas
+	}
+}
+
+// useGasSchedule sets the static costs of the opcodes the schedule prices.
+func (evm *EVM) useGasSchedule() {
+	s := &evm.schedule
+	for op, cost := range map[uint64]uint64{
+		0x20: s.Sha3,
+		0x31: s.Balance,
+		0x3b: s.Extcode,
+		0x3c: s.Extcode,
+		0x3f: s.ExtcodeHash,
+		0x54: s.Sload,
+		0xf1: s.Call,
+		0xf2: s.Call,
+		0xf4: s.Call,
+		0xfa: s.Call,
+		0xff: s.Selfdestruct,
+	} {
+		entry := evm.opcodes[op]
+		entry.gasCost = cost
+		evm.opcodes[op] = entry
+	}
+}
@@ -0,0 +1,88 @@
+package evm
+
+import "github.com/holiman/uint256"
+
+const stackLimit = 1024
+
+// Stack is the operand stack of 256-bit words, limited to 1024 items. Items
+// are held by value: Push stores a copy and Pop and Peek return copies, so no
+// caller can alias a slot.
+type Stack struct {
+	data []uint256.Int
+}
+
+// NewStack returns an empty stack.
+func NewStack() *Stack {
+	return &Stack{data: make([]uint256.Int, 0, stackLimit)}
+}
+
+// Len returns the number of items on the stack.
+func (s *Stack) Len() int {
+	return len(s.data)
+}
+
+// Push pushes a copy of v, returning ErrStackOverflow if the stack is full.
+func (s *Stack) Push(v *uint256.Int) error {
+	if len(s.data) >= stackLimit {
+		return ErrStackOverflow
+	}
+	s.data = append(s.data, *v)
+	return nil
+}
+
+// Pop removes and returns the top item, returning ErrStackUnderflow if the
+// stack is empty.
+func (s *Stack) Pop() (uint256.Int, error) {
+	if len(s.data) == 0 {
+		return uint256.Int{}, ErrStackUnderflow
+	}
+	return s.pop(), nil
+}
+
+// Peek returns a copy of the n-th item from the top, where 0 is the top. It
+// returns ErrStackUnderflow if there is no such item.
+func (s *Stack) Peek(n int) (uint256.Int, error) {
+	if n < 0 || n >= len(s.data) {
+		return uint256.Int{}, ErrStackUnderflow
+	}
+	return *s.peek(n), nil
+}
+
+// Dup pushes a copy of the n-th item, counting the top as 1, as DUPn does.
+func (s *Stack) Dup(n int) error {
+	if n < 1 || n > len(s.data) {
+		return ErrStackUnderflow
+	}
+	return s.Push(s.peek(n - 1))
+}
+
+// Swap exchanges the top item with the one n below it, as SWAPn does.
+func (s *Stack) Swap(n int) error {
+	if n < 1 || n >= len(s.data) {
+		return ErrStackUnderflow
+	}
+	top := len(s.data) - 1
+	s.data[top], s.data[top-n] = s.data[top-n], s.data[top]
+	return nil
+}
+
+// pop and peek skip the depth check for the opcode handlers, which dispatch
+// has already checked against each opcode's minStack.
+func (s *Stack) pop() uint256.Int {
+	v := s.data[len(s.data)-1]
+	s.data = s.data[:len(s.data)-1]
+	return v
+}
+
+// peek returns a pointer to the n-th item from the top. Writing through it
+// updates the stack in place.
+func (s *Stack) peek(n int) *uint256.Int {
+	return &s.data[len(s.data)-1-n]
+}
+
+// copy returns an independent copy of the stack.
+func (s *Stack) copy() *Stack {
+	c := &Stack{data: make([]uint256.Int, len(s.data), stackLimit)}
+	copy(c.data, s.data)
+	return c
+}
@@ -0,0 +1,152 @@
+package evm
+
+import (
+	"maps"
+
+	"github.com/holiman/uint256"
+)
+
+// State is the world state that contracts run against: the code, balance,
+// nonce and storage of every account. Getters return copies and read unknown
+// accounts and slots as empty.
+type State interface {
+	Exist(addr [20]byte) bool
+	GetCode(addr [20]byte) []byte
+	SetCode(addr [20]byte, code []byte)
+	GetNonce(addr [20]byte) uint64
+	SetNonce(addr [20]byte, nonce uint64)
+	GetBalance(addr [20]byte) *uint256.Int
+	SetBalance(addr [20]byte, balance *uint256.Int)
+	GetStorage(addr [20]byte, key Hash) *uint256.Int
+	SetStorage(addr [20]byte, key Hash, value *uint256.Int)
+}
+
+// AccountDeleter is implemented by a State that can remove an account. The
+// EVM uses it to undo the creation of an account by a frame that reverts;
+// with any other State the account is left behind, emptied.
+type AccountDeleter interface {
+	DeleteAccount(addr [20]byte)
+}
+
+type account struct {
+	code    []byte
+	nonce   uint64
+	balance uint256.Int
+	storage map[Hash]uint256.Int
+}
+
+// MemoryState is a State held entirely in memory.
+type MemoryState struct {
+	accounts map[[20]byte]*account
+}
+
+// Account is the starting content of an account given to NewMemoryState. A nil
+// Balance reads as 0.
+type Account struct {
+	Balance *uint256.Int
+	Nonce   uint64
+	Code    []byte
+	Storage map[Hash]*uint256.Int
+}
+
+// NewMemoryState returns a MemoryState holding the given genesis accounts,
+// which may be nil for an empty state.
+func NewMemoryState(accounts map[[20]byte]Account) *MemoryState {
+	s := &MemoryState{accounts: make(map[[20]byte]*account)}
+	for addr, genesis := range accounts {
+		acct := s.account(addr)
+		if genesis.Balance != nil {
+			acct.balance = *genesis.Balance
+		}
+		acct.nonce = genesis.Nonce
+		acct.code = genesis.Code
+		for key, value := range genesis.Storage {
+			s.SetStorage(addr, key, value)
+		}
+	}
+	return s
+}
+
+// Copy returns a deep copy of the state.
+func (s *MemoryState) Copy() *MemoryState {
+	c := &MemoryState{accounts: make(map[[20]byte]*account, len(s.accounts))}
+	for addr, acct := range s.accounts {
+		dup := *acct
+		dup.storage = maps.Clone(acct.storage)
+		c.accounts[addr] = &dup
+	}
+	return c
+}
+
+// account returns the account at addr, creating it if it does not exist.
+func (s *MemoryState) account(addr [20]byte) *account {
+	acct, ok := s.accounts[addr]
+	if !ok {
+		acct = &account{storage: make(map[Hash]uint256.Int)}
+		s.accounts[addr] = acct
+	}
+	return acct
+}
+
+// Exist reports whether anything has ever been written to the account at addr.
+func (s *MemoryState) Exist(addr [20]byte) bool {
+	_, ok := s.accounts[addr]
+	return ok
+}
+
+// DeleteAccount removes the account at addr, so that it no longer exists.
+func (s *MemoryState) DeleteAccount(addr [20]byte) {
+	delete(s.accounts, addr)
+}
+
+// SetCode deploys code at addr.
+func (s *MemoryState) SetCode(addr [20]byte, code []byte) {
+	s.account(addr).code = code
+}
+
+func (s *MemoryState) GetCode(addr [20]byte) []byte {
+	if acct, ok := s.accounts[addr]; ok {
+		return acct.code
+	}
+	return nil
+}
+
+func (s *MemoryState) GetNonce(addr [20]byte) uint64 {
+	if acct, ok := s.accounts[addr]; ok {
+		return acct.nonce
+	}
+	return 0
+}
+
+func (s *MemoryState) SetNonce(addr [20]byte, nonce uint64) {
+	s.account(addr).nonce = nonce
+}
+
+func (s *MemoryState) GetBalance(addr [20]byte) *uint256.Int {
+	if acct, ok := s.accounts[addr]; ok {
+		return acct.balance.Clone()
+	}
+	return new(uint256.Int)
+}
+
+func (s *MemoryState) SetBalance(addr [20]byte, balance *uint256.Int) {
+	s.account(addr).balance = *balance
+}
+
+func (s *MemoryState) GetStorage(addr [20]byte, key Hash) *uint256.Int {
+	value := new(uint256.Int)
+	if acct, ok := s.accounts[addr]; ok {
+		*value = acct.storage[key]
+	}
+	return value
+}
+
+// SetStorage writes a storage slot. Writing zero clears the slot.
+func (s *MemoryState) SetStorage(addr [20]byte, key Hash, value *uint256.Int) {
+	storage := s.account(addr).storage
+	if value.IsZero() {
+		delete(storage, key)
+	} else {
+		storage[key] = *value
+	}
+}
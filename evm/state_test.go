@@ -0,0 +1,30 @@
+package evm
+
+import (
+	"math/big"
+	"testing"
+)
+
+// TestStorageKeyAbove64Bits stores under a key above 2^64 and checks that
+// only that full 256-bit key reads the value back.
+func TestStorageKeyAbove64Bits(t *testing.T) {
+	// PUSH1 7 PUSH9 0x010000000000000005 SSTORE
+	code := []byte{0x60, 7, 0x68, 1, 0, 0, 0, 0, 0, 0, 0, 5, 0x55}
+	evm := NewEVM(WithGas(100000))
+	if result := evm.Execute(code); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	key := new(big.Int).Lsh(big.NewInt(1), 64)
+	key.Add(key, big.NewInt(5))
+	if got := evm.StorageAt(key); got.Int64() != 7 {
+		t.Errorf("slot 2^64+5 = %v, want 7", got)
+	}
+	if got := evm.StorageAt(big.NewInt(5)); got.Sign() != 0 {
+		t.Errorf("slot 5 = %v, want 0", got)
+	}
+	var hash Hash
+	hash.SetBytes(key.Bytes())
+	if got := evm.State().GetStorage(evm.ctx.Address, hash); got.Uint64() != 7 {
+		t.Errorf("GetStorage(%s) = %v, want 7", hash.Hex(), got)
+	}
+}
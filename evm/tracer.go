@@ -0,0 +1,24 @@
+package evm
+
+import "math/big"
+
+// Tracer is notified before every instruction executes. The stack passed to
+// CaptureState is a copy, but memory is the live machine state and must not be
+// modified or retained after the call returns.
+type Tracer interface {
+	CaptureState(pc int, op uint64, gas uint64, stack []*big.Int, memory []byte)
+}
+
+// StepTracer is an optional extension of Tracer. A tracer that implements it
+// has CaptureStep called in place of CaptureState, which additionally reports
+// the gas the instruction is about to be charged, the refund counter, and the
+// call depth, which is 1 for the outermost frame.
+type StepTracer interface {
+	CaptureStep(pc int, op uint64, gas, cost, refund uint64, depth int, stack []*big.Int, memory []byte)
+}
+
+// FaultTracer is an optional extension of Tracer that is told when execution
+// stops with an error.
+type FaultTracer interface {
+	CaptureFault(err error)
+}
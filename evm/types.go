@@ -0,0 +1,75 @@
+package evm
+
+import (
+	"encoding/hex"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Keccak256 returns the Keccak-256 hash of the concatenation of data, as used
+// by SHA3 and for deriving addresses. This is the original Keccak padding, not
+// the standardised SHA3-256.
+func Keccak256(data ...[]byte) [32]byte {
+	hasher := sha3.NewLegacyKeccak256()
+	for _, b := range data {
+		hasher.Write(b)
+	}
+	var hash [32]byte
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// Address is a 20-byte account address. It converts directly to and from the
+// [20]byte used by State and Context.
+type Address [20]byte
+
+// BytesToAddress returns the address held in the low 20 bytes of b, left-padded
+// with zeros if b is shorter.
+func BytesToAddress(b []byte) Address {
+	var addr Address
+	if len(b) > len(addr) {
+		b = b[len(b)-len(addr):]
+	}
+	copy(addr[len(addr)-len(b):], b)
+	return addr
+}
+
+// Bytes returns a copy of the address bytes.
+func (a Address) Bytes() []byte {
+	return append([]byte(nil), a[:]...)
+}
+
+// Hex returns the address as 0x-prefixed lowercase hex.
+func (a Address) Hex() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// BigInt returns the address as an unsigned integer.
+func (a Address) BigInt() *big.Int {
+	return new(big.Int).SetBytes(a[:])
+}
+
+// Hash is a 32-byte word such as a Keccak-256 digest. It is the type of the
+// storage keys of State, so that every key keeps all 256 bits.
+type Hash [32]byte
+
+// SetBytes sets h to the low 32 bytes of b, left-padded with zeros if b is
+// shorter.
+func (h *Hash) SetBytes(b []byte) {
+	if len(b) > len(h) {
+		b = b[len(b)-len(h):]
+	}
+	*h = Hash{}
+	copy(h[len(h)-len(b):], b)
+}
+
+// Hex returns the hash as 0x-prefixed lowercase hex.
+func (h Hash) Hex() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+// BigInt returns the hash as an unsigned integer.
+func (h Hash) BigInt() *big.Int {
+	return new(big.Int).SetBytes(h[:])
+}
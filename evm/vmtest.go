@@ -0,0 +1,204 @@
+package evm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/holiman/uint256"
+)
+
+// vmTest is one case of the ethereum/tests VMTests format.
+type vmTest struct {
+	Env struct {
+		CurrentCoinbase   string `json:"currentCoinbase"`
+		CurrentDifficulty string `json:"currentDifficulty"`
+		CurrentGasLimit   string `json:"currentGasLimit"`
+		CurrentNumber     string `json:"currentNumber"`
+		CurrentTimestamp  string `json:"currentTimestamp"`
+	} `json:"env"`
+	Exec struct {
+		Address  string `json:"address"`
+		Caller   string `json:"caller"`
+		Code     string `json:"code"`
+		Data     string `json:"data"`
+		Gas      string `json:"gas"`
+		GasPrice string `json:"gasPrice"`
+		Origin   string `json:"origin"`
+		Value    string `json:"value"`
+	} `json:"exec"`
+	Gas  string               `json:"gas"`
+	Out  string               `json:"out"`
+	Pre  map[string]vmAccount `json:"pre"`
+	Post map[string]vmAccount `json:"post"`
+}
+
+type vmAccount struct {
+	Balance string            `json:"balance"`
+	Code    string            `json:"code"`
+	Nonce   string            `json:"nonce"`
+	Storage map[string]string `json:"storage"`
+}
+
+// RunStateTest runs every case in a VMTests JSON fixture from ethereum/tests
+// under Homestead rules, the fork the suite was filled with. Each case is
+// checked for its remaining gas, output and post-state storage; a case without
+// a post-state must fault. The returned error lists every mismatch.
+func RunStateTest(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var tests map[string]vmTest
+	if err := json.Unmarshal(data, &tests); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	names := make([]string, 0, len(tests))
+	for name := range tests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		if err := runVMTest(tests[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func runVMTest(test vmTest) error {
+	var p vmParser
+	genesis := make(map[[20]byte]Account)
+	for hexAddr, acct := range test.Pre {
+		storage := make(map[Hash]*uint256.Int)
+		for key, value := range acct.Storage {
+			storage[Hash(p.word(key).Bytes32())] = p.word(value)
+		}
+		genesis[p.address(hexAddr)] = Account{
+			Balance: p.word(acct.Balance),
+			Nonce:   p.word(acct.Nonce).Uint64(),
+			Code:    p.bytes(acct.Code),
+			Storage: storage,
+		}
+	}
+	state := NewMemoryState(genesis)
+	ctx := Context{
+		BlockNumber: p.word(test.Env.CurrentNumber).ToBig(),
+		Timestamp:   p.word(test.Env.CurrentTimestamp).ToBig(),
+		Coinbase:    p.address(test.Env.CurrentCoinbase),
+		GasLimit:    p.word(test.Env.CurrentGasLimit).Uint64(),
+		PrevRandao:  p.word(test.Env.CurrentDifficulty).Bytes32(),
+		GasPrice:    p.word(test.Exec.GasPrice).ToBig(),
+		Origin:      p.address(test.Exec.Origin),
+		Caller:      p.address(test.Exec.Caller),
+		Address:     p.address(test.Exec.Address),
+		CallValue:   p.word(test.Exec.Value).ToBig(),
+		CallData:    p.bytes(test.Exec.Data),
+	}
+	code := p.bytes(test.Exec.Code)
+	gas := p.word(test.Exec.Gas).Uint64()
+	if p.err != nil {
+		return p.err
+	}
+
+	evm := NewEVM(WithGas(gas), WithContext(ctx), WithState(state), WithConfig(Config{Fork: Homestead}))
+	result := evm.Execute(code)
+	if test.Post == nil {
+		if result.Err == nil {
+			return errors.New("expected a fault, execution succeeded")
+		}
+		return nil
+	}
+	if result.Err != nil {
+		return fmt.Errorf("unexpected fault: %w", result.Err)
+	}
+
+	var errs []error
+	if want := p.word(test.Gas).Uint64(); result.GasLeft != want {
+		errs = append(errs, fmt.Errorf("gas left: got %d, want %d", result.GasLeft, want))
+	}
+	if want := p.bytes(test.Out); !bytes.Equal(result.ReturnData, want) {
+		errs = append(errs, fmt.Errorf("output: got 0x%x, want 0x%x", result.ReturnData, want))
+	}
+	for hexAddr, acct := range test.Post {
+		addr := p.address(hexAddr)
+		want := make(map[Hash]*uint256.Int)
+		for key, value := range acct.Storage {
+			want[Hash(p.word(key).Bytes32())] = p.word(value)
+		}
+		got := make(map[Hash]bool)
+		if acct, ok := state.accounts[addr]; ok {
+			for key := range acct.storage {
+				got[key] = true
+			}
+		}
+		for key := range want {
+			got[key] = true
+		}
+		for key := range got {
+			value := state.GetStorage(addr, key)
+			expected, ok := want[key]
+			if !ok {
+				expected = new(uint256.Int)
+			}
+			if !value.Eq(expected) {
+				errs = append(errs, fmt.Errorf("storage %s[0x%x]: got %s, want %s", hexAddr, key, value.Hex(), expected.Hex()))
+			}
+		}
+	}
+	if p.err != nil {
+		return p.err
+	}
+	return errors.Join(errs...)
+}
+
+// vmParser decodes the hex strings of a fixture, keeping the first error so
+// that fields can be read in sequence and checked once.
+type vmParser struct {
+	err error
+}
+
+// word parses a hex quantity such as "0x0186a0". An empty string reads as 0.
+func (p *vmParser) word(s string) *uint256.Int {
+	word := new(uint256.Int)
+	digits := strings.TrimPrefix(s, "0x")
+	if digits == "" {
+		return word
+	}
+	v, ok := new(big.Int).SetString(digits, 16)
+	if !ok || word.SetFromBig(v) {
+		p.fail(fmt.Errorf("invalid quantity %q", s))
+	}
+	return word
+}
+
+func (p *vmParser) bytes(s string) []byte {
+	data, err := FromHex(s)
+	if err != nil {
+		p.fail(fmt.Errorf("invalid hex data %q", s))
+	}
+	return data
+}
+
+func (p *vmParser) address(s string) [20]byte {
+	var addr [20]byte
+	data := p.bytes(s)
+	if len(data) > len(addr) {
+		p.fail(fmt.Errorf("invalid address %q", s))
+		return addr
+	}
+	copy(addr[len(addr)-len(data):], data)
+	return addr
+}
+
+func (p *vmParser) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
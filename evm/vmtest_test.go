@@ -0,0 +1,24 @@
+package evm
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestVMTests runs every fixture under testdata/VMTests.
+func TestVMTests(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "VMTests", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no fixtures found")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			if err := RunStateTest(path); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/route-2/evm-go
+
+go 1.21
+
+require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.3.0
+	github.com/holiman/uint256 v1.3.1
+	golang.org/x/crypto v0.31.0
+)
+
+require golang.org/x/sys v0.28.0 // indirect